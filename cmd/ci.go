@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ciProvider configures the CI platform that deploys the service.
+type ciProvider interface {
+	name() string
+	check() error
+	configure(cfg Config) error
+}
+
+type githubCI struct{}
+
+func (githubCI) name() string               { return "GitHub Repository" }
+func (githubCI) check() error               { return checkGH() }
+func (githubCI) configure(cfg Config) error { return configureGitHub(cfg) }
+
+type gitlabCI struct{}
+
+func (gitlabCI) name() string               { return "GitLab Project" }
+func (gitlabCI) check() error               { return checkGitLab() }
+func (gitlabCI) configure(cfg Config) error { return configureGitLab(cfg) }
+
+func ciProviderFor(name string) ciProvider {
+	if name == "gitlab" {
+		return gitlabCI{}
+	}
+	return githubCI{}
+}
+
+// ciProviderName returns GCP_CI_PROVIDER, or the provider detected from the
+// origin remote, falling back to github. Commands check detectCIProvider
+// first, so the fallback is only reached for hosts it accepted.
+func ciProviderName() string {
+	name, err := detectCIProvider()
+	if err != nil {
+		return "github"
+	}
+	return name
+}
+
+// detectCIProvider returns GCP_CI_PROVIDER, or the provider of the origin
+// remote. A remote on a host that is neither github.com, a GHE.com subdomain,
+// gitlab.com nor the configured GitHub or GitLab host is an error.
+func detectCIProvider() (string, error) {
+	if p := strings.ToLower(viper.GetString("GCP_CI_PROVIDER")); p != "" {
+		if p != "github" && p != "gitlab" {
+			return "", fmt.Errorf("GCP_CI_PROVIDER must be github or gitlab, got %q", p)
+		}
+		return p, nil
+	}
+	host, _ := originRemote()
+	switch {
+	case isGitLabHost(host):
+		return "gitlab", nil
+	case host == "" || knownGitHubHost(host):
+		return "github", nil
+	}
+	return "", fmt.Errorf("cannot tell whether %s hosts GitHub or GitLab; set GCP_GITHUB_HOST or GCP_GITLAB_URL, "+
+		"or GCP_CI_PROVIDER (--ci for init)", host)
+}
+
+// isGitLabHost reports whether host is gitlab.com or the host of GCP_GITLAB_URL.
+func isGitLabHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if host == "gitlab.com" {
+		return true
+	}
+	if configured := viper.GetString("GCP_GITLAB_URL"); configured != "" {
+		if u, err := url.Parse(configured); err == nil && u.Hostname() == host {
+			return true
+		}
+	}
+	return false
+}
+
+// knownGitHubHost reports whether host is github.com, a GHE.com subdomain or
+// the host of GCP_GITHUB_HOST.
+func knownGitHubHost(host string) bool {
+	return host == "github.com" || strings.HasSuffix(host, ".ghe.com") || host == configuredGitHubHost()
+}
+
+// configuredGitHubHost returns the host of GCP_GITHUB_HOST, if set.
+func configuredGitHubHost() string {
+	return strings.TrimSuffix(strings.TrimPrefix(viper.GetString("GCP_GITHUB_HOST"), "https://"), "/")
+}
+
+// githubHost returns GCP_GITHUB_HOST, or the host of a non-GitLab origin
+// remote, defaulting to github.com.
+func githubHost() string {
+	if h := configuredGitHubHost(); h != "" {
+		return h
+	}
+	if host, _ := originRemote(); host != "" && !isGitLabHost(host) {
+		return host
+	}
+	return "github.com"
+}
+
+func isGitHubHost(host string) bool {
+	return host == "github.com" || strings.HasSuffix(host, ".ghe.com") || host == githubHost()
+}
+
+// gitlabURL returns GCP_GITLAB_URL, or the instance of a GitLab origin remote,
+// defaulting to gitlab.com. With GCP_CI_PROVIDER=gitlab any origin host that is
+// not a GitHub host is taken as a self-hosted instance.
+func gitlabURL() string {
+	if u := viper.GetString("GCP_GITLAB_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	host, _ := originRemote()
+	if isGitLabHost(host) || host != "" && !knownGitHubHost(host) && ciProviderName() == "gitlab" {
+		return "https://" + host
+	}
+	return "https://gitlab.com"
+}
+
+// gitlabNamespace returns the group path of a GitLab project path.
+func gitlabNamespace(projectPath string) string {
+	if i := strings.LastIndex(projectPath, "/"); i >= 0 {
+		return projectPath[:i]
+	}
+	return projectPath
+}
+
+// originRemote returns the host and path of the origin remote.
+func originRemote() (host, path string) {
+	output, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", ""
+	}
+	return parseRemoteURL(strings.TrimSpace(string(output)))
+}
+
+// parseRemoteURL splits SSH (git@host:path) and HTTPS remote URLs into host
+// and repository path.
+func parseRemoteURL(remote string) (host, path string) {
+	if rest, ok := strings.CutPrefix(remote, "git@"); ok {
+		host, path, _ = strings.Cut(rest, ":")
+	} else if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, path = u.Hostname(), strings.TrimPrefix(u.Path, "/")
+	} else {
+		return "", ""
+	}
+	return host, strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+}
+
+// ciPlatformLabel is used in summaries and prompts.
+func ciPlatformLabel(cfg Config) string {
+	if cfg.CIProvider == "gitlab" {
+		return fmt.Sprintf("GitLab:               %s (%s)", cfg.GitLabProject, cfg.GitLabURL)
+	}
+	if cfg.GitHubHost != "github.com" {
+		return fmt.Sprintf("GitHub:               %s/%s (%s)", cfg.GitHubOrg, cfg.GitHubRepo, cfg.GitHubHost)
+	}
+	return fmt.Sprintf("GitHub:               %s/%s", cfg.GitHubOrg, cfg.GitHubRepo)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestIsGitLabHost(t *testing.T) {
+	tests := []struct {
+		host       string
+		configured string
+		want       bool
+	}{
+		{"gitlab.com", "", true},
+		{"", "", false},
+		{"github.com", "", false},
+		{"gitlab.example.com", "", false},
+		{"my-gitlab-mirror.github.example.com", "", false},
+		{"git.example.com", "https://git.example.com", true},
+		{"git.example.com", "https://git.example.com:8443/", true},
+		{"gitlab.example.com", "https://git.example.com", false},
+	}
+	for _, tt := range tests {
+		viper.Set("GCP_GITLAB_URL", tt.configured)
+		if got := isGitLabHost(tt.host); got != tt.want {
+			t.Errorf("isGitLabHost(%q) with GCP_GITLAB_URL=%q = %v, want %v", tt.host, tt.configured, got, tt.want)
+		}
+	}
+	viper.Set("GCP_GITLAB_URL", "")
+}
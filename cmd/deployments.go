@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var deploymentsCmd = &cobra.Command{
+	Use:   "deployments",
+	Short: "List recent Cloud Run revisions of a deployed environment",
+	Long: `Lists the most recent revisions of the Cloud Run service an environment
+deploys to, newest first, with their deploy time, the traffic they serve, the
+git commit from their commit-sha label and the image digest they run.
+
+The service, project and region are the repository defaults, overridden by
+the GCP_<ENV>_* settings of --environment.`,
+	RunE: runDeployments,
+}
+
+var deploymentsEnvironment string
+var deploymentsService string
+var deploymentsLimit int
+
+func init() {
+	rootCmd.AddCommand(deploymentsCmd)
+	deploymentsCmd.Flags().StringVarP(&deploymentsEnvironment, "environment", "e", "production",
+		"Environment to list: "+strings.Join(deployEnvironments, ", "))
+	deploymentsCmd.Flags().StringVar(&deploymentsService, "service", "",
+		"Cloud Run service (default: the environment's), e.g. a preview service")
+	deploymentsCmd.Flags().IntVarP(&deploymentsLimit, "limit", "n", 10, "Number of revisions to list")
+}
+
+// deployTarget is the Cloud Run service an environment deploys to.
+type deployTarget struct {
+	Environment string
+	ProjectID   string
+	Region      string
+	Service     string
+}
+
+func (t deployTarget) gcloudFlags() []string {
+	return []string{"--project=" + t.ProjectID, "--region=" + t.Region}
+}
+
+// resolveDeployTarget applies the GCP_<ENV>_* settings of env to the
+// repository defaults, as gcsetup service does for the CI variables.
+func resolveDeployTarget(env, service string) (deployTarget, error) {
+	if !slices.Contains(deployEnvironments, env) {
+		return deployTarget{}, fmt.Errorf("unknown environment %q, expected one of: %s",
+			env, strings.Join(deployEnvironments, ", "))
+	}
+	get := func(key, fallback string) string {
+		if v := viper.GetString(environmentKey(env, key)); v != "" {
+			return v
+		}
+		return viper.GetString(fallback)
+	}
+
+	t := deployTarget{
+		Environment: env,
+		ProjectID:   get("PROJECT_ID", "GCP_PROJECT_ID"),
+		Region:      get("REGION", "GCP_REGION"),
+		Service:     get("CLOUD_RUN_SERVICE", "GCP_CLOUD_RUN_SERVICE"),
+	}
+	if env == "staging" && promotesThroughStaging() {
+		t.Service = stagingService(viper.GetString("GCP_CLOUD_RUN_SERVICE"))
+	}
+	if service != "" {
+		t.Service = service
+	}
+
+	var missing []string
+	for key, v := range map[string]string{"GCP_PROJECT_ID": t.ProjectID, "GCP_REGION": t.Region,
+		"GCP_CLOUD_RUN_SERVICE": t.Service} {
+		if v == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return t, fmt.Errorf("missing required variables:\n  - %s", strings.Join(missing, "\n  - "))
+	}
+	return t, nil
+}
+
+type cloudRunRevision struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		CreationTimestamp string            `json:"creationTimestamp"`
+		Labels            map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		ImageDigest string `json:"imageDigest"`
+		Conditions  []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// ready reports whether the revision's Ready condition is true.
+func (r cloudRunRevision) ready() bool {
+	for _, c := range r.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// deployed returns the local creation time of the revision.
+func (r cloudRunRevision) deployed() string {
+	ts, err := time.Parse(time.RFC3339, r.Metadata.CreationTimestamp)
+	if err != nil {
+		return r.Metadata.CreationTimestamp
+	}
+	return ts.Local().Format("2006-01-02 15:04")
+}
+
+// commit returns the short git SHA from the commit-sha label, or "-".
+func (r cloudRunRevision) commit() string {
+	commit := r.Metadata.Labels["commit-sha"]
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if commit == "" {
+		commit = "-"
+	}
+	return commit
+}
+
+// digest returns the sha256 digest of the revision's image, or "-".
+func (r cloudRunRevision) digest() string {
+	if _, d, ok := strings.Cut(r.Status.ImageDigest, "@"); ok {
+		return d
+	}
+	return "-"
+}
+
+type cloudRunService struct {
+	Status struct {
+		URL                       string `json:"url"`
+		LatestReadyRevisionName   string `json:"latestReadyRevisionName"`
+		LatestCreatedRevisionName string `json:"latestCreatedRevisionName"`
+		Traffic                   []struct {
+			RevisionName   string `json:"revisionName"`
+			Percent        int    `json:"percent"`
+			Tag            string `json:"tag"`
+			LatestRevision bool   `json:"latestRevision"`
+		} `json:"traffic"`
+	} `json:"status"`
+}
+
+// traffic returns the traffic percentage of each revision.
+func (s cloudRunService) traffic() map[string]int {
+	percent := map[string]int{}
+	for _, t := range s.Status.Traffic {
+		name := t.RevisionName
+		if t.LatestRevision {
+			name = s.Status.LatestReadyRevisionName
+		}
+		percent[name] += t.Percent
+	}
+	return percent
+}
+
+// trafficPercent formats the traffic of a revision for a table, or "-".
+func trafficPercent(traffic map[string]int, revision string) string {
+	if p, ok := traffic[revision]; ok {
+		return fmt.Sprintf("%d%%", p)
+	}
+	return "-"
+}
+
+func describeService(t deployTarget) (cloudRunService, error) {
+	var svc cloudRunService
+	err := gcloudJSON(&svc, append([]string{"run", "services", "describe", t.Service}, t.gcloudFlags()...)...)
+	if err != nil {
+		return svc, fmt.Errorf("failed to describe service %s: %w", t.Service, err)
+	}
+	return svc, nil
+}
+
+func listRevisions(t deployTarget, limit int) ([]cloudRunRevision, error) {
+	var revisions []cloudRunRevision
+	args := append([]string{"run", "revisions", "list", "--service=" + t.Service,
+		"--sort-by=~metadata.creationTimestamp", fmt.Sprintf("--limit=%d", limit)}, t.gcloudFlags()...)
+	if err := gcloudJSON(&revisions, args...); err != nil {
+		return nil, fmt.Errorf("failed to list revisions of %s: %w", t.Service, err)
+	}
+	return revisions, nil
+}
+
+func runDeployments(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+	t, err := resolveDeployTarget(deploymentsEnvironment, deploymentsService)
+	if err != nil {
+		return err
+	}
+
+	svc, err := describeService(t)
+	if err != nil {
+		return err
+	}
+	revisions, err := listRevisions(t, deploymentsLimit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (%s) in %s, %s\n", t.Service, t.Environment, t.ProjectID, t.Region)
+	if svc.Status.URL != "" {
+		fmt.Println(svc.Status.URL)
+	}
+	fmt.Println()
+
+	traffic := svc.traffic()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "REVISION\tDEPLOYED\tTRAFFIC\tCOMMIT\tDIGEST")
+	for _, r := range revisions {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Metadata.Name, r.deployed(),
+			trafficPercent(traffic, r.Metadata.Name), r.commit(), r.digest())
+	}
+	return w.Flush()
+}
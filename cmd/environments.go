@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// deployEnvironments are the CI environments gcsetup creates.
+var deployEnvironments = []string{"development", "staging", "production", "preview"}
+
+// environmentKeys are the settings an environment section may override, as
+// GCP_<ENV>_<KEY>, e.g. GCP_STAGING_PROJECT_ID.
+var environmentKeys = []string{"PROJECT_ID", "PROJECT_NUMBER", "REGION", "CLOUD_RUN_SERVICE", "SERVICE_ACCOUNT_NAME"}
+
+// protectionKeys configure the protection rules of an environment.
+var protectionKeys = []string{"REVIEWERS", "WAIT_TIMER", "DEPLOYMENT_BRANCHES", "DEPLOYMENT_TAGS"}
+
+// Environment holds the resolved values of an environment with its own config
+// section. They are set as environment-scoped secrets and variables, which
+// take precedence over the repository-level ones in jobs using the environment.
+type Environment struct {
+	Name                     string
+	ProjectID                string
+	ProjectNumber            string
+	ServiceAccountName       string
+	ServiceAccountEmail      string
+	CloudRunService          string
+	CloudRunRegion           string
+	WorkloadIdentityProvider string
+}
+
+// EnvironmentProtection holds the protection rules of an environment.
+// Reviewers are user logins or org/team slugs; Branches and Tags are name
+// patterns allowed to deploy, where the branch "protected" stands for all
+// protected branches.
+type EnvironmentProtection struct {
+	Reviewers []string
+	WaitTimer int
+	Branches  []string
+	Tags      []string
+}
+
+func (p EnvironmentProtection) configured() bool {
+	return len(p.Reviewers) > 0 || p.WaitTimer > 0 || len(p.Branches) > 0 || len(p.Tags) > 0
+}
+
+func environmentKey(env, key string) string {
+	return "GCP_" + strings.ToUpper(env) + "_" + key
+}
+
+// hasOwnProject reports whether the environment deploys to a different project
+// than the repository default.
+func (e Environment) hasOwnProject(cfg Config) bool {
+	return e.ProjectID != cfg.ProjectID
+}
+
+// loadEnvironments resolves the environment sections of the config against
+// cfg. Project numbers of other projects are looked up when not configured.
+func loadEnvironments(cfg *Config, dry bool) error {
+	cfg.Environments = nil
+	cfg.Protection = map[string]EnvironmentProtection{}
+	for _, name := range deployEnvironments {
+		protection, err := loadProtection(name)
+		if err != nil {
+			return err
+		}
+		if protection.configured() {
+			cfg.Protection[name] = protection
+		}
+
+		// Staging needs its own service name when the workflow deploys to it.
+		configured := name == "staging" && promotesThroughStaging()
+		for _, key := range environmentKeys {
+			if viper.GetString(environmentKey(name, key)) != "" {
+				configured = true
+			}
+		}
+		if !configured {
+			continue
+		}
+
+		get := func(key, fallback string) string {
+			if v := viper.GetString(environmentKey(name, key)); v != "" {
+				return v
+			}
+			return fallback
+		}
+		env := Environment{
+			Name:               name,
+			ProjectID:          get("PROJECT_ID", cfg.ProjectID),
+			ServiceAccountName: get("SERVICE_ACCOUNT_NAME", cfg.ServiceAccountName),
+			CloudRunService:    get("CLOUD_RUN_SERVICE", cfg.CloudRunService),
+			CloudRunRegion:     get("REGION", cfg.CloudRunRegion),
+		}
+		if name == "staging" && promotesThroughStaging() {
+			env.CloudRunService = stagingService(cfg.CloudRunService)
+		}
+		env.ProjectNumber = viper.GetString(environmentKey(name, "PROJECT_NUMBER"))
+		if env.ProjectNumber == "" && !env.hasOwnProject(*cfg) {
+			env.ProjectNumber = cfg.ProjectNumber
+		}
+		if env.ProjectNumber == "" {
+			number, err := projectNumber(env.ProjectID, dry)
+			if err != nil {
+				return fmt.Errorf("failed to get project number of %s for %s: %w", env.ProjectID, name, err)
+			}
+			env.ProjectNumber = number
+		}
+		env.ServiceAccountEmail = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", env.ServiceAccountName, env.ProjectID)
+		env.WorkloadIdentityProvider = cfg.WIF.Issuer.providerName(env.ProjectNumber)
+		cfg.Environments = append(cfg.Environments, env)
+	}
+	return nil
+}
+
+func loadProtection(env string) (EnvironmentProtection, error) {
+	p := EnvironmentProtection{
+		Reviewers: splitList(viper.GetString(environmentKey(env, "REVIEWERS"))),
+		Branches:  splitList(viper.GetString(environmentKey(env, "DEPLOYMENT_BRANCHES"))),
+		Tags:      splitList(viper.GetString(environmentKey(env, "DEPLOYMENT_TAGS"))),
+	}
+	key := environmentKey(env, "WAIT_TIMER")
+	if timer := viper.GetString(key); timer != "" {
+		minutes, err := strconv.Atoi(timer)
+		if err != nil || minutes < 0 || minutes > 43200 {
+			return p, fmt.Errorf("%s must be a number of minutes between 0 and 43200", key)
+		}
+		p.WaitTimer = minutes
+	}
+	return p, nil
+}
+
+func projectNumber(projectID string, dry bool) (string, error) {
+	if dry {
+		return "PROJECT_NUMBER", nil
+	}
+	output, err := exec.Command("gcloud", "projects", "describe", projectID,
+		"--format=value(projectNumber)").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// setupEnvironmentProject prepares a project that only an environment deploys
+// to: Workload Identity Federation for its service account, and read access
+// for its Cloud Run service agent to the images built in the main project.
+func setupEnvironmentProject(cfg Config, env Environment) error {
+	if err := ensureWorkloadIdentity(dryRun, wifTarget{
+		ProjectID:           env.ProjectID,
+		ProjectNumber:       env.ProjectNumber,
+		ServiceAccountEmail: env.ServiceAccountEmail,
+		Policy:              cfg.WIF,
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println("  Granting image pull access...")
+	agent := fmt.Sprintf("serviceAccount:service-%s@serverless-robot-prod.iam.gserviceaccount.com", env.ProjectNumber)
+	if err := runGcloud(dryRun, "artifacts", "repositories", "add-iam-policy-binding", cfg.ArtifactRegistryName,
+		"--project="+cfg.ProjectID,
+		"--location="+cfg.ArtifactRegistryLocation,
+		"--role=roles/artifactregistry.reader",
+		"--member="+agent,
+	); err != nil {
+		return fmt.Errorf("failed to grant %s access to %s: %w", agent, cfg.ArtifactRegistryName, err)
+	}
+	fmt.Printf("  ✓ %s can pull from %s\n", env.ProjectID, cfg.ArtifactRegistryURL)
+	return nil
+}
+
+// environmentConfigLines renders the environment sections for saveConfig.
+func environmentConfigLines() string {
+	var b strings.Builder
+	for _, env := range deployEnvironments {
+		header := false
+		for _, key := range append(append(environmentKeys, protectionKeys...), "WIF_REFS") {
+			v := viper.GetString(environmentKey(env, key))
+			if v == "" {
+				continue
+			}
+			if !header {
+				fmt.Fprintf(&b, "\n# %s environment\n", env)
+				header = true
+			}
+			fmt.Fprintf(&b, "%s=%s\n", environmentKey(env, key), v)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gcsetup/internal/github"
+)
+
+// checkGH makes sure a GitHub token for the configured host is available.
+func checkGH() error {
+	_, err := github.Token(githubHost())
+	return err
+}
+
+func newGitHubClient(host string) (*github.Client, error) {
+	token, err := github.Token(host)
+	if err != nil {
+		return nil, err
+	}
+	return github.NewClient(host, token), nil
+}
+
+type ciValue struct {
+	name  string
+	value string
+}
+
+// ciValues returns the repository-level secrets and variables of cfg.
+func ciValues(cfg Config) (secrets, variables []ciValue) {
+	secrets = []ciValue{
+		{"GCP_SERVICE_ACCOUNT", cfg.ServiceAccountEmail},
+		{"GCP_WORKLOAD_IDENTITY_PROVIDER", cfg.WorkloadIdentityProvider},
+	}
+	variables = []ciValue{
+		{"GCP_PROJECT_ID", cfg.ProjectID},
+		{"GCP_REGION", cfg.CloudRunRegion},
+		{"GCP_CLOUD_RUN_SERVICE", cfg.CloudRunService},
+		{"GCP_ARTIFACT_REGISTRY", cfg.ArtifactRegistryName},
+	}
+	return secrets, variables
+}
+
+// ciValues returns the environment-scoped secrets and variables. The
+// artifact registry is shared: images are built once in the main project.
+func (e Environment) ciValues() (secrets, variables []ciValue) {
+	secrets = []ciValue{
+		{"GCP_SERVICE_ACCOUNT", e.ServiceAccountEmail},
+		{"GCP_WORKLOAD_IDENTITY_PROVIDER", e.WorkloadIdentityProvider},
+	}
+	variables = []ciValue{
+		{"GCP_PROJECT_ID", e.ProjectID},
+		{"GCP_REGION", e.CloudRunRegion},
+		{"GCP_CLOUD_RUN_SERVICE", e.CloudRunService},
+	}
+	return secrets, variables
+}
+
+// configureGitHub sets the Actions secrets and variables the workflow needs
+// and creates the deployment environments. Environments with a config section
+// get their own secrets and variables. Each scope is reconciled with
+// setGitHubValues.
+func configureGitHub(cfg Config) error {
+	repo := github.Repo{Owner: cfg.GitHubOrg, Name: cfg.GitHubRepo}
+	secrets, variables := ciValues(cfg)
+
+	if dryRun {
+		printGitHubDryRun(repo, secrets, variables)
+		fmt.Println("  Creating environments...")
+		for _, env := range deployEnvironments {
+			fmt.Printf("    [dry-run] PUT /repos/%s/environments/%s\n", repo, env)
+			printProtectionDryRun(cfg.Protection[env])
+		}
+		for _, env := range cfg.Environments {
+			envSecrets, envVariables := env.ciValues()
+			printGitHubDryRun(github.Repo{Owner: repo.Owner, Name: repo.Name, Environment: env.Name},
+				envSecrets, envVariables)
+		}
+		if cfg.BranchProtection {
+			fmt.Println("  Protecting default branch...")
+			fmt.Printf("    [dry-run] POST /repos/%s/rulesets %s (checks: %s, approvals: %d)\n",
+				repo, deployRulesetName, strings.Join(requiredChecks, ", "), cfg.RequiredApprovals)
+		}
+		return nil
+	}
+
+	client, err := newGitHubClient(cfg.GitHubHost)
+	if err != nil {
+		return err
+	}
+
+	if err := setGitHubValues(client, repo, secrets, variables); err != nil {
+		return err
+	}
+
+	fmt.Println("  Creating environments...")
+	for _, env := range deployEnvironments {
+		fmt.Printf("    %s\n", env)
+		protection, ok := cfg.Protection[env]
+		if !ok {
+			if err := client.CreateEnvironment(repo, env, nil); err != nil {
+				fmt.Printf("    ⚠ Could not create environment %s (may require admin access)\n", env)
+			}
+			continue
+		}
+		if err := applyProtection(client, repo, env, protection); err != nil {
+			return fmt.Errorf("environment %s: %w", env, err)
+		}
+	}
+
+	for _, env := range cfg.Environments {
+		envSecrets, envVariables := env.ciValues()
+		envRepo := github.Repo{Owner: repo.Owner, Name: repo.Name, Environment: env.Name}
+		if err := setGitHubValues(client, envRepo, envSecrets, envVariables); err != nil {
+			return fmt.Errorf("environment %s: %w", env.Name, err)
+		}
+	}
+
+	if cfg.BranchProtection {
+		fmt.Println("  Protecting default branch...")
+		if err := ensureDeployRuleset(client, repo, cfg.RequiredApprovals); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Protection["production"].Reviewers) == 0 {
+		fmt.Println()
+		fmt.Println("  💡 Tip: Set GCP_PRODUCTION_REVIEWERS to require reviewers before")
+		fmt.Println("     production deployments")
+	}
+
+	return nil
+}
+
+// environmentSettings resolves the reviewers of p to IDs.
+func environmentSettings(client *github.Client, p EnvironmentProtection) (*github.EnvironmentSettings, error) {
+	settings := &github.EnvironmentSettings{WaitTimer: p.WaitTimer, Reviewers: []github.Reviewer{}}
+	for _, r := range p.Reviewers {
+		r = strings.TrimPrefix(r, "@")
+		if teamOrg, slug, ok := strings.Cut(r, "/"); ok {
+			id, err := client.TeamID(teamOrg, slug)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve team %s: %w", r, err)
+			}
+			settings.Reviewers = append(settings.Reviewers, github.Reviewer{Type: "Team", ID: id})
+			continue
+		}
+		id, err := client.UserID(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve user %s: %w", r, err)
+		}
+		settings.Reviewers = append(settings.Reviewers, github.Reviewer{Type: "User", ID: id})
+	}
+
+	switch {
+	case slices.Contains(p.Branches, "protected"):
+		if len(p.Branches) > 1 || len(p.Tags) > 0 {
+			return nil, fmt.Errorf("deployment branch \"protected\" cannot be combined with other branches or tags")
+		}
+		settings.DeploymentBranchPolicy = &github.BranchPolicy{ProtectedBranches: true}
+	case len(p.Branches) > 0 || len(p.Tags) > 0:
+		settings.DeploymentBranchPolicy = &github.BranchPolicy{CustomBranchPolicies: true}
+	}
+	return settings, nil
+}
+
+// applyProtection creates or updates the environment with its protection
+// rules and replaces its deployment branch and tag policies.
+func applyProtection(client *github.Client, repo github.Repo, env string, p EnvironmentProtection) error {
+	settings, err := environmentSettings(client, p)
+	if err != nil {
+		return err
+	}
+	if err := client.CreateEnvironment(repo, env, settings); err != nil {
+		return fmt.Errorf("failed to apply protection rules: %w", err)
+	}
+	if len(p.Reviewers) > 0 {
+		fmt.Printf("      ✓ reviewers: %s\n", strings.Join(p.Reviewers, ", "))
+	}
+	if p.WaitTimer > 0 {
+		fmt.Printf("      ✓ wait timer: %d min\n", p.WaitTimer)
+	}
+	if settings.DeploymentBranchPolicy == nil {
+		return nil
+	}
+	if !settings.DeploymentBranchPolicy.CustomBranchPolicies {
+		fmt.Printf("      ✓ deployments from %s only\n", policySummary(p))
+		return nil
+	}
+
+	var wanted []github.DeploymentPolicy
+	for _, b := range p.Branches {
+		wanted = append(wanted, github.DeploymentPolicy{Name: b, Type: "branch"})
+	}
+	for _, t := range p.Tags {
+		wanted = append(wanted, github.DeploymentPolicy{Name: t, Type: "tag"})
+	}
+
+	existing, err := client.ListDeploymentPolicies(repo, env)
+	if err != nil {
+		return fmt.Errorf("failed to list deployment policies: %w", err)
+	}
+	for _, e := range existing {
+		if e.Type == "" {
+			e.Type = "branch"
+		}
+		if !slices.Contains(wanted, github.DeploymentPolicy{Name: e.Name, Type: e.Type}) {
+			if err := client.DeleteDeploymentPolicy(repo, env, e.ID); err != nil {
+				return fmt.Errorf("failed to remove %s policy %s: %w", e.Type, e.Name, err)
+			}
+			fmt.Printf("      - %s %s\n", e.Type, e.Name)
+		}
+	}
+	for _, w := range wanted {
+		if slices.ContainsFunc(existing, func(e github.DeploymentPolicy) bool {
+			return e.Name == w.Name && (e.Type == w.Type || e.Type == "" && w.Type == "branch")
+		}) {
+			continue
+		}
+		if err := client.CreateDeploymentPolicy(repo, env, w); err != nil {
+			return fmt.Errorf("failed to add %s policy %s: %w", w.Type, w.Name, err)
+		}
+	}
+	fmt.Printf("      ✓ deployments from %s only\n", policySummary(p))
+	return nil
+}
+
+func policySummary(p EnvironmentProtection) string {
+	var refs []string
+	for _, b := range p.Branches {
+		if b == "protected" {
+			refs = append(refs, "protected branches")
+			continue
+		}
+		refs = append(refs, "branch "+b)
+	}
+	for _, t := range p.Tags {
+		refs = append(refs, "tag "+t)
+	}
+	return strings.Join(refs, ", ")
+}
+
+func printProtectionDryRun(p EnvironmentProtection) {
+	if !p.configured() {
+		return
+	}
+	if len(p.Reviewers) > 0 {
+		fmt.Printf("      reviewers: %s\n", strings.Join(p.Reviewers, ", "))
+	}
+	if p.WaitTimer > 0 {
+		fmt.Printf("      wait timer: %d min\n", p.WaitTimer)
+	}
+	if len(p.Branches) > 0 || len(p.Tags) > 0 {
+		fmt.Printf("      deployments from %s only\n", policySummary(p))
+	}
+}
+
+func printGitHubDryRun(repo github.Repo, secrets, variables []ciValue) {
+	prefix := repo.ActionsPath()
+	scope := ""
+	if repo.Environment != "" {
+		scope = " (" + repo.Environment + ")"
+	}
+	fmt.Printf("  Setting secrets%s...\n", scope)
+	for _, s := range secrets {
+		fmt.Printf("    [dry-run] PUT %s/secrets/%s\n", prefix, s.name)
+	}
+	fmt.Printf("  Setting variables%s...\n", scope)
+	for _, v := range variables {
+		fmt.Printf("    [dry-run] POST %s/variables %s=%s\n", prefix, v.name, v.value)
+	}
+}
+
+// syncCounts tallies what a reconcile did.
+type syncCounts struct {
+	created, updated, unchanged int
+}
+
+func (c syncCounts) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d unchanged", c.created, c.updated, c.unchanged)
+}
+
+// secretHashKeyPath holds the HMAC key of the secret hashes. It is kept out
+// of version control, so the committed state cannot be used to test guesses
+// of a secret's value.
+func secretHashKeyPath() string {
+	return filepath.Join(stateDir, "secret-hash.key")
+}
+
+// secretHashKey reads the HMAC key, generating it on first use. Another
+// checkout gets its own key, so it sees tracked secrets as changed and
+// overwrites them once.
+func secretHashKey() ([]byte, error) {
+	path := secretHashKeyPath()
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return hex.DecodeString(strings.TrimSpace(string(data)))
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	ensureGitignore(path)
+	return key, nil
+}
+
+// secretHash is what the state file records instead of a secret's value.
+func secretHash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// setGitHubValues reconciles the secrets and variables of one scope.
+// Variables are compared by value. Secret values cannot be read back, so an
+// existing secret is overwritten with --force or when the state file records
+// a different hash for it; untracked secrets are left alone.
+func setGitHubValues(client *github.Client, repo github.Repo, secrets, variables []ciValue) error {
+	scope := ""
+	if repo.Environment != "" {
+		scope = " (" + repo.Environment + ")"
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if st.SecretHashes == nil {
+		st.SecretHashes = map[string]string{}
+	}
+	hashKey, err := secretHashKey()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", secretHashKeyPath(), err)
+	}
+
+	fmt.Printf("  Setting secrets%s...\n", scope)
+	existingSecrets, err := client.ListSecrets(repo)
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	isSet := map[string]bool{}
+	for _, s := range existingSecrets {
+		isSet[s.Name] = true
+	}
+	var counts syncCounts
+	var key *github.PublicKey
+	for _, s := range secrets {
+		stateKey := repo.ActionsPath() + "/secrets/" + s.name
+		hash := secretHash(hashKey, s.value)
+		recorded, tracked := st.SecretHashes[stateKey]
+		switch {
+		case !isSet[s.name]:
+			fmt.Printf("    %s (created)\n", s.name)
+			counts.created++
+		case forceSecrets || tracked && recorded != hash:
+			fmt.Printf("    %s (updated)\n", s.name)
+			counts.updated++
+		case !tracked:
+			fmt.Printf("    %s (already set, not tracked; use --force to overwrite)\n", s.name)
+			counts.unchanged++
+			continue
+		default:
+			fmt.Printf("    %s (unchanged)\n", s.name)
+			counts.unchanged++
+			continue
+		}
+		if key == nil {
+			if key, err = client.PublicKey(repo); err != nil {
+				return fmt.Errorf("failed to get public key of %s: %w", repo, err)
+			}
+		}
+		if err := client.SetSecret(repo, key, s.name, s.value); err != nil {
+			return fmt.Errorf("failed to set secret %s: %w", s.name, err)
+		}
+		st.SecretHashes[stateKey] = hash
+	}
+	if counts.created+counts.updated > 0 {
+		if err := saveState(st); err != nil {
+			return fmt.Errorf("failed to record secret hashes: %w", err)
+		}
+	}
+	fmt.Printf("  ✓ Secrets: %s\n", counts)
+
+	fmt.Printf("  Setting variables%s...\n", scope)
+	existingVariables, err := client.ListVariables(repo)
+	if err != nil {
+		return fmt.Errorf("failed to list variables: %w", err)
+	}
+	current := map[string]string{}
+	for _, v := range existingVariables {
+		current[v.Name] = v.Value
+	}
+	counts = syncCounts{}
+	for _, v := range variables {
+		value, ok := current[v.name]
+		switch {
+		case !ok:
+			fmt.Printf("    %s (created)\n", v.name)
+			err = client.CreateVariable(repo, v.name, v.value)
+			counts.created++
+		case value != v.value:
+			fmt.Printf("    %s (updated: %s → %s)\n", v.name, value, v.value)
+			err = client.UpdateVariable(repo, v.name, v.value)
+			counts.updated++
+		default:
+			fmt.Printf("    %s (unchanged)\n", v.name)
+			counts.unchanged++
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set variable %s: %w", v.name, err)
+		}
+	}
+	fmt.Printf("  ✓ Variables: %s\n", counts)
+	return nil
+}
+
+// deployRulesetName identifies the ruleset gcsetup manages.
+const deployRulesetName = "gcsetup-deploy"
+
+// requiredChecks are the job names of gcloud-deploy.yml that must pass before
+// a pull request can be merged into the default branch.
+var requiredChecks = []string{"Test", "Build"}
+
+// deployRuleset requires pull requests with approvals and passing Test and
+// Build checks on the default branch, and forbids force pushes and deletion,
+// so every production deploy goes through the workflow.
+func deployRuleset(approvals int) github.Ruleset {
+	var checks []map[string]any
+	for _, c := range requiredChecks {
+		checks = append(checks, map[string]any{"context": c})
+	}
+	rs := github.Ruleset{
+		Name:        deployRulesetName,
+		Target:      "branch",
+		Enforcement: "active",
+		Rules: []github.Rule{
+			{Type: "deletion"},
+			{Type: "non_fast_forward"},
+			{Type: "pull_request", Parameters: map[string]any{
+				"required_approving_review_count":   approvals,
+				"dismiss_stale_reviews_on_push":     true,
+				"require_code_owner_review":         false,
+				"require_last_push_approval":        false,
+				"required_review_thread_resolution": false,
+			}},
+			{Type: "required_status_checks", Parameters: map[string]any{
+				"strict_required_status_checks_policy": true,
+				"required_status_checks":               checks,
+			}},
+		},
+	}
+	rs.Conditions.RefName.Include = []string{"~DEFAULT_BRANCH"}
+	rs.Conditions.RefName.Exclude = []string{}
+	return rs
+}
+
+// ensureDeployRuleset creates the deploy ruleset or replaces the rules of an
+// existing one with the same name.
+func ensureDeployRuleset(client *github.Client, repo github.Repo, approvals int) error {
+	rulesets, err := client.ListRulesets(repo)
+	if err != nil {
+		return fmt.Errorf("failed to list rulesets: %w", err)
+	}
+	rs := deployRuleset(approvals)
+	for _, existing := range rulesets {
+		if existing.Name == deployRulesetName {
+			if err := client.UpdateRuleset(repo, existing.ID, rs); err != nil {
+				return fmt.Errorf("failed to update ruleset %s: %w", deployRulesetName, err)
+			}
+			fmt.Printf("    ✓ Ruleset %s updated\n", deployRulesetName)
+			return nil
+		}
+	}
+	if err := client.CreateRuleset(repo, rs); err != nil {
+		return fmt.Errorf("failed to create ruleset %s (requires admin access): %w", deployRulesetName, err)
+	}
+	fmt.Printf("    ✓ Ruleset %s created: %s required, %d approval(s)\n",
+		deployRulesetName, strings.Join(requiredChecks, " and "), approvals)
+	return nil
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// gitlabToken returns a GitLab personal or project access token with the api
+// scope.
+func gitlabToken() string {
+	if t := viper.GetString("GCP_GITLAB_TOKEN"); t != "" {
+		return t
+	}
+	return os.Getenv("GITLAB_TOKEN")
+}
+
+func checkGitLab() error {
+	if gitlabToken() == "" {
+		return fmt.Errorf("GitLab token not found. Set GITLAB_TOKEN to a token with the api scope")
+	}
+	return nil
+}
+
+// gitlabAPI calls the GitLab REST API of the configured instance and decodes
+// the response into out when it is not nil.
+func gitlabAPI(cfg Config, method, path string, form url.Values, out any) (int, error) {
+	status, _, err := gitlabSend(cfg, method, path, form, out)
+	return status, err
+}
+
+// gitlabSend is gitlabAPI returning the response headers as well.
+func gitlabSend(cfg Config, method, path string, form url.Values, out any) (int, http.Header, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, cfg.GitLabURL+"/api/v4"+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", gitlabToken())
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, resp.Header, err
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, resp.Header, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status,
+			strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		return resp.StatusCode, resp.Header, json.Unmarshal(data, out)
+	}
+	return resp.StatusCode, resp.Header, nil
+}
+
+// gitlabVariables lists the CI/CD variables of a project, following the
+// X-Next-Page header of GitLab's offset pagination.
+func gitlabVariables(cfg Config, project string) ([]gitlabVariable, error) {
+	var all []gitlabVariable
+	for page := "1"; page != ""; {
+		var vars []gitlabVariable
+		_, header, err := gitlabSend(cfg, "GET", project+"/variables?per_page=100&page="+page, nil, &vars)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, vars...)
+		page = header.Get("X-Next-Page")
+	}
+	return all, nil
+}
+
+type gitlabVariable struct {
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	EnvironmentScope string `json:"environment_scope"`
+}
+
+// configureGitLab sets the CI/CD variables the pipeline needs and creates the
+// deployment environments. Secrets become masked variables; environments with
+// a config section get variables scoped to them. Unlike GitHub secrets, masked
+// values can be read back, so all variables are compared by value.
+func configureGitLab(cfg Config) error {
+	project := "/projects/" + url.PathEscape(cfg.GitLabProject)
+
+	var existing []gitlabVariable
+	if !dryRun {
+		var err error
+		if existing, err = gitlabVariables(cfg, project); err != nil {
+			return fmt.Errorf("failed to list CI/CD variables: %w", err)
+		}
+	}
+	current := map[string]string{}
+	for _, v := range existing {
+		current[v.Key+"@"+v.EnvironmentScope] = v.Value
+	}
+
+	setVariables := func(scope string, secrets, variables []ciValue) error {
+		label := ""
+		if scope != "*" {
+			label = " (" + scope + ")"
+		}
+		fmt.Printf("  Setting CI/CD variables%s...\n", label)
+		var counts syncCounts
+		for i, v := range append(secrets, variables...) {
+			masked := i < len(secrets)
+			if dryRun {
+				fmt.Printf("    [dry-run] POST %s/variables key=%s environment_scope=%s masked=%v\n",
+					project, v.name, scope, masked)
+				continue
+			}
+			form := url.Values{
+				"value":             {v.value},
+				"masked":            {fmt.Sprint(masked)},
+				"environment_scope": {scope},
+			}
+			value, ok := current[v.name+"@"+scope]
+			var err error
+			switch {
+			case !ok:
+				fmt.Printf("    %s (created)\n", v.name)
+				form.Set("key", v.name)
+				_, err = gitlabAPI(cfg, "POST", project+"/variables", form, nil)
+				counts.created++
+			case value != v.value || forceSecrets:
+				fmt.Printf("    %s (updated)\n", v.name)
+				_, err = gitlabAPI(cfg, "PUT", project+"/variables/"+url.PathEscape(v.name)+
+					"?filter[environment_scope]="+url.QueryEscape(scope), form, nil)
+				counts.updated++
+			default:
+				fmt.Printf("    %s (unchanged)\n", v.name)
+				counts.unchanged++
+			}
+			if err != nil {
+				return fmt.Errorf("failed to set variable %s: %w", v.name, err)
+			}
+		}
+		if !dryRun {
+			fmt.Printf("  ✓ Variables: %s\n", counts)
+		}
+		return nil
+	}
+
+	secrets, variables := ciValues(cfg)
+	if err := setVariables("*", secrets, variables); err != nil {
+		return err
+	}
+	for _, env := range cfg.Environments {
+		secrets, variables := env.ciValues()
+		if err := setVariables(gitlabEnvironmentScope(env.Name), secrets, variables); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("  Creating environments...")
+	for _, env := range deployEnvironments {
+		fmt.Printf("    %s\n", env)
+		if dryRun {
+			fmt.Printf("    [dry-run] POST %s/environments name=%s\n", project, env)
+			continue
+		}
+		status, err := gitlabAPI(cfg, "POST", project+"/environments", url.Values{"name": {env}}, nil)
+		if err != nil && status != http.StatusBadRequest && status != http.StatusConflict {
+			fmt.Printf("    ⚠ Could not create environment %s (may require maintainer access)\n", env)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("  💡 Tip: Protect 'production' in GitLab → Operate → Environments")
+	fmt.Println("     and require approvals before deployment")
+
+	return nil
+}
+
+// gitlabEnvironmentScope returns the variable scope of an environment. Preview
+// jobs run in one preview/mr-<iid> environment per merge request, so their
+// variables are scoped to preview/*.
+func gitlabEnvironmentScope(env string) string {
+	if env == "preview" {
+		return env + "/*"
+	}
+	return env
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestGitLabVariablesFollowsNextPage(t *testing.T) {
+	pages := [][]string{{"GCP_PROJECT_ID", "GCP_REGION"}, {"GCP_SERVICE_ACCOUNT"}, {"GCP_CLOUD_RUN_SERVICE"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/acme%2Fshop/variables" {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("PRIVATE-TOKEN") != "test-token" {
+			t.Errorf("PRIVATE-TOKEN = %q", r.Header.Get("PRIVATE-TOKEN"))
+		}
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 || page > len(pages) {
+			t.Errorf("invalid page %q", r.URL.Query().Get("page"))
+			http.NotFound(w, r)
+			return
+		}
+		if page < len(pages) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		} else {
+			// GitLab sends the header empty on the last page.
+			w.Header().Set("X-Next-Page", "")
+		}
+		var vars []gitlabVariable
+		for _, key := range pages[page-1] {
+			vars = append(vars, gitlabVariable{Key: key, Value: "v", EnvironmentScope: "*"})
+		}
+		_ = json.NewEncoder(w).Encode(vars)
+	}))
+	defer srv.Close()
+	t.Setenv("GITLAB_TOKEN", "test-token")
+
+	vars, err := gitlabVariables(Config{GitLabURL: srv.URL}, "/projects/acme%2Fshop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, v := range vars {
+		keys = append(keys, v.Key)
+	}
+	want := []string{"GCP_PROJECT_ID", "GCP_REGION", "GCP_SERVICE_ACCOUNT", "GCP_CLOUD_RUN_SERVICE"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+}
+
+func TestGitLabEnvironmentScope(t *testing.T) {
+	for env, want := range map[string]string{
+		"production": "production",
+		"staging":    "staging",
+		"preview":    "preview/*",
+	} {
+		if got := gitlabEnvironmentScope(env); got != want {
+			t.Errorf("gitlabEnvironmentScope(%s) = %s, want %s", env, got, want)
+		}
+	}
+}
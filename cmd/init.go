@@ -5,8 +5,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"gcsetup/embedded"
-
 	"github.com/spf13/cobra"
 )
 
@@ -15,12 +13,29 @@ var initCmd = &cobra.Command{
 	Short: "Initialize project with workflow and .env.gcloud template",
 	Long: `Creates the following files in your project:
   - .github/workflows/gcloud-deploy.yml  (CI/CD workflow)
-  - .env.gcloud                   (environment variables template)`,
+    or .gitlab-ci.yml with --ci gitlab  (CI/CD pipeline)
+  - .env.gcloud                   (environment variables template)
+
+The CI provider is detected from the origin remote unless --ci is given.
+The workflow is rendered from the service settings and the GCP_WORKFLOW_*
+settings of an existing .env.gcloud. Its test job comes from a language
+template detected from the project files (see gcsetup templates list) unless
+--template is given.
+
+Templates in .gcsetup/templates, GCP_TEMPLATE_DIR or the user config
+directory override or extend the built-in ones. The template version is
+recorded in .gcsetup so that gcsetup workflow upgrade can later merge
+template changes into the file.`,
 	RunE: runInit,
 }
 
+var initCI string
+var initTemplate string
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initCI, "ci", "", "CI provider: github or gitlab (default: detected)")
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "Language template (default: detected)")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -29,22 +44,68 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	workflowDir := filepath.Join(cwd, ".github", "workflows")
-	if err := os.MkdirAll(workflowDir, 0755); err != nil {
-		return fmt.Errorf("failed to create .github/workflows directory: %w", err)
+	ci := initCI
+	if ci == "" {
+		if ci, err = detectCIProvider(); err != nil {
+			return err
+		}
 	}
 
-	workflowPath := filepath.Join(workflowDir, "gcloud-deploy.yml")
-	if err := writeFileIfNotExists(workflowPath, embedded.DeployWorkflow); err != nil {
+	data, err := loadWorkflowData()
+	if err != nil {
 		return err
 	}
-	fmt.Println("✓ Created", workflowPath)
 
-	envPath := filepath.Join(cwd, ".env.gcloud")
-	if err := writeFileIfNotExists(envPath, embedded.EnvTemplate); err != nil {
+	language := initTemplate
+	if language == "" {
+		language = detectLanguage(cwd)
+	}
+	lang, err := findLanguageTemplate(language)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using %s template: %s\n", lang.Name, lang.Description)
+
+	path, _, err := ciFile(ci)
+	if err != nil {
+		return err
+	}
+	r, err := renderCIFile(ci, lang, data)
+	if err != nil {
 		return err
 	}
-	fmt.Println("✓ Created", envPath)
+	for _, file := range r.Overrides {
+		fmt.Println("Using template override:", file)
+	}
+	fullPath := filepath.Join(cwd, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", filepath.Dir(path), err)
+	}
+	if err := writeFileIfNotExists(fullPath, r.Content); err != nil {
+		return err
+	}
+	fmt.Println("✓ Created", fullPath)
+	if err := recordWorkflow(path, ci, lang.Name, r.Version, r.Content); err != nil {
+		fmt.Println("⚠ Could not record the template version:", err)
+	}
+
+	envPath := filepath.Join(cwd, ".env.gcloud")
+	if _, err := os.Stat(envPath); err == nil {
+		fmt.Println("✓ Kept existing", envPath)
+	} else {
+		env, source, err := envTemplate()
+		if err != nil {
+			return err
+		}
+		if err := writeFileIfNotExists(envPath, env); err != nil {
+			return err
+		}
+		if source != "" {
+			fmt.Printf("✓ Created %s from %s\n", envPath, source)
+		} else {
+			fmt.Println("✓ Created", envPath)
+		}
+	}
 
 	gitignorePath := filepath.Join(cwd, ".gitignore")
 	if err := appendToGitignore(gitignorePath); err != nil {
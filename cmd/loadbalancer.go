@@ -25,39 +25,214 @@ var lbSetupCmd = &cobra.Command{
   2. Configure backend services with custom URL routing
   3. Set up URL maps for path-based routing
   4. Create target HTTP(S) proxies
-  5. Configure frontend IPs and forwarding rules`,
+  5. Configure frontend IPs and forwarding rules
+  6. Enable request logging and create a monitoring dashboard and alert policies
+
+Pass --spec to read the configuration from a YAML file instead of prompting.
+Routes in the spec split the traffic of a path between weighted services:
+
+  name: gcloud-lb
+  services:
+    - name: api
+      path: /api/*
+    - name: api-v2
+  routes:
+    - path: /api/*
+      backends:
+        - service: api
+          weight: 90
+        - service: api-v2
+          weight: 10`,
 	RunE: runLoadBalancer,
 }
 
 var lbDryRun bool
 var lbNonInteractive bool
+var lbSpecFile string
+var lbLogSampleRate float64
+var lbNoMonitoring bool
 
 func init() {
 	rootCmd.AddCommand(loadbalancerCmd)
 	loadbalancerCmd.AddCommand(lbSetupCmd)
-	lbSetupCmd.Flags().BoolVar(&lbDryRun, "dry-run", false, "Print commands without executing")
-	lbSetupCmd.Flags().BoolVarP(&lbNonInteractive, "yes", "y", false, "Non-interactive mode (accept all defaults)")
+	loadbalancerCmd.PersistentFlags().BoolVar(&lbDryRun, "dry-run", false, "Print commands without executing")
+	loadbalancerCmd.PersistentFlags().BoolVarP(&lbNonInteractive, "yes", "y", false,
+		"Non-interactive mode (accept all defaults)")
+	lbSetupCmd.Flags().StringVar(&lbSpecFile, "spec", "", "Load balancer spec file (YAML) instead of prompts")
+	lbSetupCmd.Flags().Float64Var(&lbLogSampleRate, "log-sample-rate", 1.0,
+		"Fraction of requests logged by the backend services (0 disables logging)")
+	lbSetupCmd.Flags().BoolVar(&lbNoMonitoring, "no-monitoring", false,
+		"Skip the monitoring dashboard and alert policies")
 }
 
 type LoadBalancerService struct {
-	Name        string
-	Protocol    string
-	Port        int
-	Path        string
-	HealthCheck string
+	Name        string            `yaml:"name"`
+	Backend     string            `yaml:"backend,omitempty"`
+	Protocol    string            `yaml:"protocol,omitempty"`
+	Port        int               `yaml:"port,omitempty"`
+	Path        string            `yaml:"path,omitempty"`
+	Paths       []string          `yaml:"paths,omitempty"`
+	HealthCheck string            `yaml:"healthCheck,omitempty"`
+	NEGs        []LoadBalancerNEG `yaml:"negs,omitempty"`
+}
+
+// LoadBalancerNEG is a serverless network endpoint group in front of a Cloud
+// Run service. Services with NEGs need no health check.
+type LoadBalancerNEG struct {
+	Name            string `yaml:"name"`
+	Region          string `yaml:"region"`
+	CloudRunService string `yaml:"cloudRunService,omitempty"`
+}
+
+// backend returns the backend service name, which follows the naming scheme
+// unless the service was imported from a hand-built load balancer.
+func (s LoadBalancerService) backend() string {
+	if s.Backend != "" {
+		return s.Backend
+	}
+	return backendName(s.Name)
+}
+
+func (s LoadBalancerService) allPaths() []string {
+	var paths []string
+	if s.Path != "" {
+		paths = append(paths, s.Path)
+	}
+	return append(paths, s.Paths...)
+}
+
+// LoadBalancerRoute splits the traffic of a path between several services.
+type LoadBalancerRoute struct {
+	Path     string            `yaml:"path"`
+	Backends []WeightedBackend `yaml:"backends"`
+}
+
+type WeightedBackend struct {
+	Service string `yaml:"service"`
+	Weight  int    `yaml:"weight"`
+}
+
+// lbResources holds the names of the global resources that make up a load balancer.
+type lbResources struct {
+	URLMap             string
+	Proxy              string
+	ForwardingRule     string
+	Address            string
+	ForwardingRuleIPv6 string
+	AddressIPv6        string
+}
+
+// lbResourceNames returns the naming scheme names, overridden by the names
+// recorded in the state file for imported load balancers.
+func lbResourceNames(lbName string) lbResources {
+	names := lbResources{
+		URLMap:             lbName + "-url-map",
+		Proxy:              lbName + "-proxy",
+		ForwardingRule:     lbName + "-forwarding-rule",
+		Address:            lbName + "-ip",
+		ForwardingRuleIPv6: lbName + "-ipv6-forwarding-rule",
+		AddressIPv6:        lbName + "-ipv6",
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return names
+	}
+	lb, ok := st.LoadBalancers[lbName]
+	if !ok {
+		return names
+	}
+	if lb.URLMap != "" {
+		names.URLMap = lb.URLMap
+	}
+	if lb.Proxy != "" {
+		names.Proxy = lb.Proxy
+	}
+	if lb.ForwardingRule != "" {
+		names.ForwardingRule = lb.ForwardingRule
+	}
+	if lb.Address != "" {
+		names.Address = lb.Address
+	}
+	if lb.ForwardingRuleIPv6 != "" {
+		names.ForwardingRuleIPv6 = lb.ForwardingRuleIPv6
+	}
+	if lb.AddressIPv6 != "" {
+		names.AddressIPv6 = lb.AddressIPv6
+	}
+	return names
+}
+
+func backendName(service string) string {
+	return service + "-backend"
 }
 
 type LoadBalancerConfig struct {
-	ProjectID       string
-	ProjectNumber   string
-	LBName          string
-	Region          string
-	Network         string
-	Subnet          string
-	Services        []LoadBalancerService
-	HealthCheckPort int
-	UseSSL          bool
-	SSLCertificate  string
+	ProjectID       string                  `yaml:"projectId,omitempty"`
+	ProjectNumber   string                  `yaml:"-"`
+	LBName          string                  `yaml:"name"`
+	Region          string                  `yaml:"region,omitempty"`
+	Network         string                  `yaml:"network,omitempty"`
+	Subnet          string                  `yaml:"subnet,omitempty"`
+	Services        []LoadBalancerService   `yaml:"services"`
+	Routes          []LoadBalancerRoute     `yaml:"routes,omitempty"`
+	HealthCheckPort int                     `yaml:"healthCheckPort,omitempty"`
+	UseSSL          bool                    `yaml:"ssl,omitempty"`
+	SSLCertificate  string                  `yaml:"sslCertificate,omitempty"`
+	IPv6            bool                    `yaml:"ipv6,omitempty"`
+	Hosts           []string                `yaml:"hosts,omitempty"`
+	DNS             *LoadBalancerDNS        `yaml:"dns,omitempty"`
+	Logging         *LoadBalancerLogging    `yaml:"logging,omitempty"`
+	Monitoring      *LoadBalancerMonitoring `yaml:"monitoring,omitempty"`
+}
+
+// LoadBalancerLogging configures request logging on every backend service.
+// An unset sample rate logs every request; use Disabled to turn logging off.
+type LoadBalancerLogging struct {
+	Disabled   bool    `yaml:"disabled,omitempty"`
+	SampleRate float64 `yaml:"sampleRate,omitempty"`
+}
+
+// LoadBalancerMonitoring configures the Cloud Monitoring dashboard and the
+// per-backend alert policies created for the load balancer.
+type LoadBalancerMonitoring struct {
+	Disabled             bool     `yaml:"disabled,omitempty"`
+	ErrorRatio           float64  `yaml:"errorRatio,omitempty"`
+	LatencyP95Ms         int      `yaml:"latencyP95Ms,omitempty"`
+	NotificationChannels []string `yaml:"notificationChannels,omitempty"`
+}
+
+// backendFor returns the backend service name of the named service.
+func (cfg LoadBalancerConfig) backendFor(service string) string {
+	for _, svc := range cfg.Services {
+		if svc.Name == service {
+			return svc.backend()
+		}
+	}
+	return backendName(service)
+}
+
+// serviceFor returns the name of the service whose backend service is
+// backend. Backends missing from the spec follow the naming scheme.
+func (cfg LoadBalancerConfig) serviceFor(backend string) string {
+	for _, svc := range cfg.Services {
+		if svc.backend() == backend {
+			return svc.Name
+		}
+	}
+	return trimBackendSuffix(backend)
+}
+
+func trimBackendSuffix(backend string) string {
+	return strings.TrimSuffix(backend, "-backend")
+}
+
+// LoadBalancerDNS enables the Cloud DNS step, which points Hosts at the
+// reserved IP address of the load balancer.
+type LoadBalancerDNS struct {
+	Zone   string `yaml:"zone,omitempty"`
+	Domain string `yaml:"domain,omitempty"`
+	TTL    int    `yaml:"ttl,omitempty"`
 }
 
 func runLoadBalancer(cmd *cobra.Command, args []string) error {
@@ -81,7 +256,17 @@ func runLoadBalancer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("GCP_PROJECT_ID is required")
 	}
 
-	if !lbNonInteractive {
+	if lbSpecFile != "" {
+		spec, err := loadLBSpec(lbSpecFile)
+		if err != nil {
+			return err
+		}
+		if spec.ProjectID == "" {
+			spec.ProjectID = cfg.ProjectID
+		}
+		spec.ProjectNumber = cfg.ProjectNumber
+		cfg = *spec
+	} else if !lbNonInteractive {
 		if err := interactiveLBConfig(&cfg); err != nil {
 			return err
 		}
@@ -93,6 +278,21 @@ func runLoadBalancer(cmd *cobra.Command, args []string) error {
 		cfg.UseSSL = false
 	}
 
+	if cfg.Logging == nil {
+		cfg.Logging = &LoadBalancerLogging{SampleRate: 1.0}
+	}
+	if cmd.Flags().Changed("log-sample-rate") {
+		cfg.Logging.SampleRate = lbLogSampleRate
+		cfg.Logging.Disabled = lbLogSampleRate == 0
+	}
+	if cfg.Logging.SampleRate < 0 || cfg.Logging.SampleRate > 1 {
+		return fmt.Errorf("log sample rate must be between 0 and 1")
+	}
+	if cfg.Monitoring == nil {
+		cfg.Monitoring = &LoadBalancerMonitoring{}
+	}
+	cfg.Monitoring.Disabled = cfg.Monitoring.Disabled || lbNoMonitoring
+
 	fmt.Println()
 	fmt.Println("==============================================")
 	fmt.Println("  Load Balancer Configuration Summary")
@@ -101,12 +301,25 @@ func runLoadBalancer(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Network:              %s\n", cfg.Network)
 	fmt.Printf("  Health Check Port:    %d\n", cfg.HealthCheckPort)
 	fmt.Printf("  Use SSL:              %v\n", cfg.UseSSL)
+	fmt.Printf("  IPv6:                 %v\n", cfg.IPv6)
+	if cfg.Logging.Disabled {
+		fmt.Println("  Request Logging:      disabled")
+	} else {
+		fmt.Printf("  Request Logging:      %.0f%% of requests\n", cfg.Logging.SampleRate*100)
+	}
+	fmt.Printf("  Monitoring:           %v\n", !cfg.Monitoring.Disabled)
 	fmt.Printf("  Number of Services:   %d\n", len(cfg.Services))
 	fmt.Println()
 	for i, svc := range cfg.Services {
 		fmt.Printf("  Service %d: %s\n", i+1, svc.Name)
 		fmt.Printf("    Protocol: %s, Port: %d, Path: %s\n", svc.Protocol, svc.Port, svc.Path)
 	}
+	for _, route := range cfg.Routes {
+		fmt.Printf("  Route %s:\n", route.Path)
+		for _, b := range route.Backends {
+			fmt.Printf("    %3d%% -> %s\n", b.Weight, b.Service)
+		}
+	}
 	fmt.Println("==============================================")
 	fmt.Println()
 
@@ -128,6 +341,18 @@ func runLoadBalancer(cmd *cobra.Command, args []string) error {
 		{"Creating HTTP(S) Proxy", createHTTPSProxy},
 		{"Creating Forwarding Rule", createForwardingRule},
 	}
+	if !cfg.Monitoring.Disabled {
+		steps = append(steps, struct {
+			name string
+			fn   func(LoadBalancerConfig) error
+		}{"Configuring Monitoring", configureLBMonitoring})
+	}
+	if cfg.DNS != nil && len(cfg.Hosts) > 0 {
+		steps = append(steps, struct {
+			name string
+			fn   func(LoadBalancerConfig) error
+		}{"Configuring DNS", configureLBDNS})
+	}
 
 	for i, step := range steps {
 		fmt.Printf("Step %d/%d: %s...\n", i+1, len(steps), step.name)
@@ -138,15 +363,32 @@ func runLoadBalancer(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if lbSpecFile != "" {
+		if err := recordLBSpec(cfg.LBName, lbSpecFile); err != nil {
+			fmt.Printf("Warning: Could not update state file: %v\n", err)
+		}
+	}
+
 	fmt.Println("==============================================")
 	fmt.Println("  Load Balancer Configuration Complete!")
 	fmt.Println("==============================================")
 	fmt.Println()
 	fmt.Printf("Load Balancer Name: %s\n", cfg.LBName)
 	fmt.Println("Next steps:")
+	if cfg.DNS != nil && len(cfg.Hosts) > 0 {
+		fmt.Println("  1. Wait for the DNS records to propagate")
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		fmt.Printf("  2. Test the configuration with curl %s://%s\n", scheme, cfg.Hosts[0])
+		return nil
+	}
 	fmt.Println("  1. Get the load balancer IP:")
-	fmt.Printf("     gcloud compute forwarding-rules describe %s --global\n", cfg.LBName+"-forwarding-rule")
+	fmt.Printf("     gcloud compute forwarding-rules describe %s --global\n",
+		lbResourceNames(cfg.LBName).ForwardingRule)
 	fmt.Println("  2. Create a DNS record pointing to the load balancer IP")
+	fmt.Println("     (or set hosts and dns in a spec file to let gcsetup manage Cloud DNS)")
 	fmt.Println("  3. Test the configuration with curl")
 
 	return nil
@@ -218,11 +460,30 @@ func interactiveLBConfig(cfg *LoadBalancerConfig) error {
 		fmt.Println()
 	}
 
+	hosts := promptLB("Domain names for Cloud DNS (comma-separated, leave empty to skip)", "")
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			cfg.Hosts = append(cfg.Hosts, h)
+		}
+	}
+	if len(cfg.Hosts) > 0 {
+		cfg.DNS = &LoadBalancerDNS{
+			Zone: promptLB("Cloud DNS managed zone (leave empty to detect)", ""),
+		}
+		cfg.DNS.Domain = promptLB("Domain of a new zone, if none exists (e.g. example.com)", "")
+	}
+	cfg.IPv6 = strings.EqualFold(promptLB("Also serve IPv6 on a reserved IPv6 address (y/n)", "n"), "y")
+
 	return nil
 }
 
 func createHealthChecks(cfg LoadBalancerConfig) error {
 	for _, service := range cfg.Services {
+		if len(service.NEGs) > 0 {
+			fmt.Printf("  ✓ Service '%s' uses serverless NEGs, no health check needed\n", service.Name)
+			continue
+		}
+
 		if lbDryRun {
 			cmd := fmt.Sprintf(
 				`gcloud compute health-checks create http "%s" \
@@ -262,97 +523,171 @@ func createHealthChecks(cfg LoadBalancerConfig) error {
 }
 
 func createBackendServices(cfg LoadBalancerConfig) error {
-	for i, service := range cfg.Services {
-		backendName := fmt.Sprintf("%s-backend", service.Name)
+	for _, service := range cfg.Services {
+		backendName := service.backend()
 
 		protocol := "HTTP"
 		if service.Protocol == "HTTPS" {
 			protocol = "HTTPS"
 		}
 
+		var logFlags []string
+		if cfg.Logging != nil && cfg.Logging.Disabled {
+			logFlags = []string{"--no-enable-logging"}
+		} else if cfg.Logging != nil {
+			logFlags = []string{
+				"--enable-logging",
+				fmt.Sprintf("--logging-sample-rate=%g", cfg.Logging.SampleRate),
+			}
+		}
+
 		if lbDryRun {
-			fmt.Printf("  [dry-run] Creating backend service '%s'\n", backendName)
+			fmt.Printf("  [dry-run] Creating backend service '%s' (%s)\n", backendName, strings.Join(logFlags, " "))
+			for _, neg := range service.NEGs {
+				fmt.Printf("  [dry-run] Attaching serverless NEG '%s' (%s)\n", neg.Name, neg.Region)
+			}
 			continue
 		}
 
 		if err := exec.Command("gcloud", "compute", "backend-services", "describe",
 			backendName, "--global", "--project", cfg.ProjectID).Run(); err == nil {
 			fmt.Printf("  ✓ Backend service '%s' already exists\n", backendName)
-			cfg.Services[i].HealthCheck = backendName
-			continue
+			if cfg.Logging != nil {
+				args := append([]string{"compute", "backend-services", "update", backendName,
+					"--global", "--project=" + cfg.ProjectID}, logFlags...)
+				if err := runGcloud(false, args...); err != nil {
+					return fmt.Errorf("failed to update logging of %s: %w", backendName, err)
+				}
+			}
+		} else {
+			fmt.Printf("  Creating backend service '%s'...\n", backendName)
+			parts := []string{
+				"compute", "backend-services", "create", backendName,
+				"--global",
+				"--load-balancing-scheme=EXTERNAL",
+				"--enable-cdn",
+				"--project=" + cfg.ProjectID,
+			}
+			parts = append(parts, logFlags...)
+			if len(service.NEGs) == 0 {
+				parts = append(parts,
+					"--protocol="+protocol,
+					"--port-name=http",
+					"--health-checks="+service.HealthCheck,
+				)
+			}
+			if err := exec.Command("gcloud", parts...).Run(); err != nil {
+				return fmt.Errorf("failed to create backend service: %w", err)
+			}
+			fmt.Printf("  ✓ Backend service '%s' created\n", backendName)
 		}
 
-		fmt.Printf("  Creating backend service '%s'...\n", backendName)
-		parts := []string{
-			"compute", "backend-services", "create", backendName,
-			"--global",
-			"--protocol=" + protocol,
-			"--port-name=http",
-			"--health-checks=" + service.HealthCheck,
-			"--load-balancing-scheme=EXTERNAL",
-			"--enable-cdn",
-			"--project=" + cfg.ProjectID,
-		}
-		if err := exec.Command("gcloud", parts...).Run(); err != nil {
-			return fmt.Errorf("failed to create backend service: %w", err)
+		if err := attachServerlessNEGs(cfg.ProjectID, backendName, service.NEGs); err != nil {
+			return err
 		}
-		fmt.Printf("  ✓ Backend service '%s' created\n", backendName)
-		cfg.Services[i].HealthCheck = backendName
 	}
 
 	return nil
 }
 
-func createURLMap(cfg LoadBalancerConfig) error {
-	urlMapName := fmt.Sprintf("%s-url-map", cfg.LBName)
-
-	if lbDryRun {
-		fmt.Printf("  [dry-run] Creating URL map '%s'\n", urlMapName)
+// attachServerlessNEGs creates the serverless NEGs of a backend service and
+// adds the ones that are not attached yet.
+func attachServerlessNEGs(projectID, backend string, negs []LoadBalancerNEG) error {
+	if len(negs) == 0 {
 		return nil
 	}
 
-	if err := exec.Command("gcloud", "compute", "url-maps", "describe",
-		urlMapName, "--project", cfg.ProjectID).Run(); err == nil {
-		fmt.Printf("  ✓ URL map '%s' already exists\n", urlMapName)
-		return nil
+	var bs lbBackendService
+	if err := gcloudJSON(&bs, "compute", "backend-services", "describe", backend,
+		"--global", "--project="+projectID); err != nil {
+		return fmt.Errorf("failed to describe backend service %s: %w", backend, err)
+	}
+	attached := map[string]bool{}
+	for _, b := range bs.Backends {
+		attached[resourceName(b.Group)] = true
 	}
 
-	defaultBackend := fmt.Sprintf("%s-backend", cfg.Services[0].Name)
+	for _, neg := range negs {
+		if !gcloudExists("compute", "network-endpoint-groups", "describe", neg.Name,
+			"--region="+neg.Region, "--project="+projectID) {
+			if neg.CloudRunService == "" {
+				return fmt.Errorf("NEG %s does not exist and has no cloudRunService", neg.Name)
+			}
+			fmt.Printf("  Creating serverless NEG '%s' for Cloud Run service '%s'...\n", neg.Name, neg.CloudRunService)
+			if err := runGcloud(false, "compute", "network-endpoint-groups", "create", neg.Name,
+				"--region="+neg.Region,
+				"--network-endpoint-type=serverless",
+				"--cloud-run-service="+neg.CloudRunService,
+				"--project="+projectID,
+			); err != nil {
+				return fmt.Errorf("failed to create NEG %s: %w", neg.Name, err)
+			}
+		}
 
-	fmt.Printf("  Creating URL map '%s'...\n", urlMapName)
-	parts := []string{
-		"compute", "url-maps", "create", urlMapName,
-		"--default-service=" + defaultBackend,
-		"--project=" + cfg.ProjectID,
+		if attached[neg.Name] {
+			continue
+		}
+		if err := runGcloud(false, "compute", "backend-services", "add-backend", backend,
+			"--global",
+			"--network-endpoint-group="+neg.Name,
+			"--network-endpoint-group-region="+neg.Region,
+			"--project="+projectID,
+		); err != nil {
+			return fmt.Errorf("failed to attach NEG %s to %s: %w", neg.Name, backend, err)
+		}
+		fmt.Printf("  ✓ NEG '%s' attached to '%s'\n", neg.Name, backend)
 	}
+	return nil
+}
 
-	if err := exec.Command("gcloud", parts...).Run(); err != nil {
-		return fmt.Errorf("failed to create URL map: %w", err)
-	}
+func createURLMap(cfg LoadBalancerConfig) error {
+	urlMapName := lbResourceNames(cfg.LBName).URLMap
 
-	for _, service := range cfg.Services {
-		backendName := fmt.Sprintf("%s-backend", service.Name)
+	if len(cfg.Services) == 0 {
+		return fmt.Errorf("at least one service is required")
+	}
 
-		parts := []string{
-			"compute", "url-maps", "add-path-rule", urlMapName,
-			"--service=" + backendName,
-			"--path-pattern=" + service.Path,
-			"--project=" + cfg.ProjectID,
+	m := &urlMap{Name: urlMapName}
+	if !lbDryRun && gcloudExists("compute", "url-maps", "describe", urlMapName,
+		"--global", "--project="+cfg.ProjectID) {
+		existing, err := exportURLMap(cfg.ProjectID, urlMapName)
+		if err != nil {
+			return err
 		}
+		m = existing
+		fmt.Printf("  Updating URL map '%s'...\n", urlMapName)
+	} else {
+		fmt.Printf("  Creating URL map '%s'...\n", urlMapName)
+	}
 
-		fmt.Printf("  Adding path rule '%s' -> %s\n", service.Path, backendName)
-		if err := exec.Command("gcloud", parts...).Run(); err != nil {
-			fmt.Printf("  ⚠ Could not add path rule (may already exist): %v\n", err)
+	if m.DefaultService == "" {
+		m.DefaultService = backendServiceURL(cfg.ProjectID, cfg.Services[0].backend())
+	}
+
+	for _, service := range cfg.Services {
+		for _, path := range service.allPaths() {
+			fmt.Printf("  Path rule '%s' -> %s\n", path, service.backend())
+			m.setPathRule(path, backendServiceURL(cfg.ProjectID, service.backend()))
 		}
 	}
 
-	fmt.Printf("  ✓ URL map '%s' created\n", urlMapName)
+	for _, route := range cfg.Routes {
+		fmt.Printf("  Route rule '%s' -> %s\n", route.Path, cfg.formatWeights(route.Backends))
+		m.setWeightedRoute(route.Path, cfg.weightedBackendServices(cfg.ProjectID, route.Backends))
+	}
+
+	if err := importURLMap(cfg.ProjectID, m); err != nil {
+		return err
+	}
+
+	fmt.Printf("  ✓ URL map '%s' configured\n", urlMapName)
 	return nil
 }
 
 func createHTTPSProxy(cfg LoadBalancerConfig) error {
-	proxyName := fmt.Sprintf("%s-proxy", cfg.LBName)
-	urlMapName := fmt.Sprintf("%s-url-map", cfg.LBName)
+	names := lbResourceNames(cfg.LBName)
+	proxyName := names.Proxy
+	urlMapName := names.URLMap
 
 	if lbDryRun {
 		fmt.Printf("  [dry-run] Creating HTTP(S) proxy '%s'\n", proxyName)
@@ -365,7 +700,7 @@ func createHTTPSProxy(cfg LoadBalancerConfig) error {
 	}
 
 	if err := exec.Command("gcloud", "compute",
-		fmt.Sprintf("%s-proxies", strings.ToLower(protocol)), "describe",
+		fmt.Sprintf("target-%s-proxies", strings.ToLower(protocol)), "describe",
 		proxyName, "--global", "--project", cfg.ProjectID).Run(); err == nil {
 		fmt.Printf("  ✓ %s proxy '%s' already exists\n", protocol, proxyName)
 		return nil
@@ -375,7 +710,7 @@ func createHTTPSProxy(cfg LoadBalancerConfig) error {
 
 	if protocol == "HTTP" {
 		parts := []string{
-			"compute", "http-proxies", "create", proxyName,
+			"compute", "target-http-proxies", "create", proxyName,
 			"--url-map=" + urlMapName,
 			"--project=" + cfg.ProjectID,
 		}
@@ -384,7 +719,7 @@ func createHTTPSProxy(cfg LoadBalancerConfig) error {
 		}
 	} else {
 		parts := []string{
-			"compute", "https-proxies", "create", proxyName,
+			"compute", "target-https-proxies", "create", proxyName,
 			"--url-map=" + urlMapName,
 			"--ssl-certificates=" + cfg.SSLCertificate,
 			"--project=" + cfg.ProjectID,
@@ -398,9 +733,42 @@ func createHTTPSProxy(cfg LoadBalancerConfig) error {
 	return nil
 }
 
+// createForwardingRule creates the IPv4 forwarding rule and, with ipv6 set,
+// a second rule on a reserved IPv6 address, as a global forwarding rule
+// serves a single IP version.
 func createForwardingRule(cfg LoadBalancerConfig) error {
-	ruleName := fmt.Sprintf("%s-forwarding-rule", cfg.LBName)
-	proxyName := fmt.Sprintf("%s-proxy", cfg.LBName)
+	names := lbResourceNames(cfg.LBName)
+	if err := createGlobalForwardingRule(cfg, names.ForwardingRule, names.Address); err != nil {
+		return err
+	}
+	if !cfg.IPv6 {
+		return nil
+	}
+	if err := reserveIPv6Address(cfg, names.AddressIPv6); err != nil {
+		return err
+	}
+	return createGlobalForwardingRule(cfg, names.ForwardingRuleIPv6, names.AddressIPv6)
+}
+
+func reserveIPv6Address(cfg LoadBalancerConfig, name string) error {
+	if lbDryRun {
+		fmt.Printf("  [dry-run] Reserving IPv6 address '%s'\n", name)
+		return nil
+	}
+	if gcloudExists("compute", "addresses", "describe", name, "--global", "--project="+cfg.ProjectID) {
+		fmt.Printf("  ✓ IPv6 address '%s' already exists\n", name)
+		return nil
+	}
+	if err := runGcloud(false, "compute", "addresses", "create", name,
+		"--global", "--ip-version=IPV6", "--project="+cfg.ProjectID); err != nil {
+		return fmt.Errorf("failed to reserve IPv6 address: %w", err)
+	}
+	fmt.Printf("  ✓ IPv6 address '%s' reserved\n", name)
+	return nil
+}
+
+func createGlobalForwardingRule(cfg LoadBalancerConfig, ruleName, address string) error {
+	proxyName := lbResourceNames(cfg.LBName).Proxy
 	protocol := "HTTP"
 	port := 80
 
@@ -426,7 +794,7 @@ func createForwardingRule(cfg LoadBalancerConfig) error {
 		"compute", "forwarding-rules", "create", ruleName,
 		"--global",
 		fmt.Sprintf("--target-%s-proxy=%s", strings.ToLower(protocol), proxyName),
-		fmt.Sprintf("--address=%s-ip", cfg.LBName),
+		"--address=" + address,
 		fmt.Sprintf("--ports=%d", port),
 		"--project=" + cfg.ProjectID,
 	}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lbDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a load balancer and its resources",
+	Long: `Delete a load balancer created by gcsetup, in dependency order:
+  1. Forwarding rules (<name>-forwarding-rule, <name>-ipv6-forwarding-rule)
+  2. HTTP(S) proxy (<name>-proxy)
+  3. URL map (<name>-url-map)
+  4. Backend services and health checks referenced by the URL map
+  5. Reserved IP addresses (<name>-ip, <name>-ipv6), only with --release-ip
+
+Afterwards the load balancer is removed from the state file. Its spec file
+is left in place unless --delete-spec is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLBDelete,
+}
+
+var lbKeepBackends bool
+var lbReleaseIP bool
+var lbDeleteSpec bool
+
+func init() {
+	loadbalancerCmd.AddCommand(lbDeleteCmd)
+	lbDeleteCmd.Flags().BoolVar(&lbKeepBackends, "keep-backends", false,
+		"Keep backend services and health checks")
+	lbDeleteCmd.Flags().BoolVar(&lbReleaseIP, "release-ip", false,
+		"Also release the reserved IP address")
+	lbDeleteCmd.Flags().BoolVar(&lbDeleteSpec, "delete-spec", false, "Also delete the spec file of the load balancer")
+}
+
+func runLBDelete(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	lbName := args[0]
+	projectID := viper.GetString("GCP_PROJECT_ID")
+	if projectID == "" {
+		return fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+	names := lbResourceNames(lbName)
+
+	var backends []string
+	if !lbKeepBackends {
+		if m, err := exportURLMap(projectID, names.URLMap); err == nil {
+			backends = m.services()
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Deleting Load Balancer '%s'\n", lbName)
+	fmt.Println("==============================================")
+	fmt.Printf("  Forwarding rule:  %s\n", names.ForwardingRule)
+	fmt.Printf("  Forwarding rule:  %s (if present)\n", names.ForwardingRuleIPv6)
+	fmt.Printf("  Proxy:            %s\n", names.Proxy)
+	fmt.Printf("  URL map:          %s\n", names.URLMap)
+	for _, b := range backends {
+		fmt.Printf("  Backend service:  %s\n", b)
+	}
+	if lbReleaseIP {
+		fmt.Printf("  IP address:       %s\n", names.Address)
+		fmt.Printf("  IP address:       %s (if present)\n", names.AddressIPv6)
+	}
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	if !lbNonInteractive {
+		if !promptConfirm("Delete these resources?") {
+			fmt.Println("Deletion cancelled.")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	resources := []struct {
+		kind string
+		args []string
+	}{
+		{"Forwarding rule", []string{"forwarding-rules", "delete", names.ForwardingRule, "--global"}},
+		{"Forwarding rule", []string{"forwarding-rules", "delete", names.ForwardingRuleIPv6, "--global"}},
+		{"HTTPS proxy", []string{"target-https-proxies", "delete", names.Proxy, "--global"}},
+		{"HTTP proxy", []string{"target-http-proxies", "delete", names.Proxy, "--global"}},
+		{"URL map", []string{"url-maps", "delete", names.URLMap, "--global"}},
+	}
+
+	for _, r := range resources {
+		name := r.args[2]
+		describe := append([]string{"compute", r.args[0], "describe", name}, r.args[3:]...)
+		if !lbDryRun && !gcloudExists(append(describe, "--project="+projectID)...) {
+			continue
+		}
+		deleteArgs := append([]string{"compute"}, r.args...)
+		deleteArgs = append(deleteArgs, "--quiet", "--project="+projectID)
+		if err := runGcloud(lbDryRun, deleteArgs...); err != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", r.kind, name, err)
+		}
+		fmt.Printf("  ✓ %s '%s' deleted\n", r.kind, name)
+	}
+
+	for _, b := range backends {
+		if err := deleteBackendService(projectID, b); err != nil {
+			return err
+		}
+	}
+
+	if lbReleaseIP {
+		if err := runGcloud(lbDryRun, "compute", "addresses", "delete", names.Address,
+			"--global", "--quiet", "--project="+projectID); err != nil {
+			return fmt.Errorf("failed to release IP address %s: %w", names.Address, err)
+		}
+		fmt.Printf("  ✓ IP address '%s' released\n", names.Address)
+
+		if lbDryRun || gcloudExists("compute", "addresses", "describe", names.AddressIPv6,
+			"--global", "--project="+projectID) {
+			if err := runGcloud(lbDryRun, "compute", "addresses", "delete", names.AddressIPv6,
+				"--global", "--quiet", "--project="+projectID); err != nil {
+				return fmt.Errorf("failed to release IP address %s: %w", names.AddressIPv6, err)
+			}
+			fmt.Printf("  ✓ IP address '%s' released\n", names.AddressIPv6)
+		}
+	}
+
+	if err := forgetLB(lbName); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Println("  Load Balancer Deleted")
+	fmt.Println("==============================================")
+
+	return nil
+}
+
+// forgetLB removes a deleted load balancer from the state file, so its
+// recorded resource names no longer apply, and deletes its spec file when
+// --delete-spec is set.
+func forgetLB(lbName string) error {
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	lb, ok := st.LoadBalancers[lbName]
+	if !ok {
+		return nil
+	}
+
+	if lbDryRun {
+		fmt.Printf("  [dry-run] Would remove '%s' from %s\n", lbName, statePath())
+		if lb.Spec != "" && lbDeleteSpec {
+			fmt.Printf("  [dry-run] Would delete spec file %s\n", lb.Spec)
+		}
+		return nil
+	}
+
+	if lb.Spec != "" && lbDeleteSpec {
+		if err := os.Remove(lb.Spec); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete spec file %s: %w", lb.Spec, err)
+		}
+		fmt.Printf("  ✓ Spec file %s deleted\n", lb.Spec)
+	}
+	delete(st.LoadBalancers, lbName)
+	if err := saveState(st); err != nil {
+		return fmt.Errorf("failed to update %s: %w", statePath(), err)
+	}
+	fmt.Printf("  ✓ Removed from %s\n", statePath())
+	return nil
+}
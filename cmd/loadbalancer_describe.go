@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lbDescribeCmd = &cobra.Command{
+	Use:   "describe <name>",
+	Short: "Show the live topology of a load balancer",
+	Long: `Render the live load balancer topology as a tree:
+  forwarding rule → proxy → URL map → path rules → backend services → health checks / backends`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLBDescribe,
+}
+
+func init() {
+	loadbalancerCmd.AddCommand(lbDescribeCmd)
+}
+
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+func (n *treeNode) add(format string, a ...any) *treeNode {
+	child := &treeNode{label: fmt.Sprintf(format, a...)}
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *treeNode) print() {
+	fmt.Println(n.label)
+	n.printChildren("")
+}
+
+func (n *treeNode) printChildren(indent string) {
+	for i, child := range n.children {
+		branch, next := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Println(indent + branch + child.label)
+		child.printChildren(indent + next)
+	}
+}
+
+type lbForwardingRule struct {
+	IPAddress  string `json:"IPAddress"`
+	IPProtocol string `json:"IPProtocol"`
+	PortRange  string `json:"portRange"`
+	Target     string `json:"target"`
+}
+
+type lbProxy struct {
+	URLMap          string   `json:"urlMap"`
+	SSLCertificates []string `json:"sslCertificates"`
+}
+
+type lbBackendService struct {
+	Protocol     string   `json:"protocol"`
+	HealthChecks []string `json:"healthChecks"`
+	EnableCDN    bool     `json:"enableCDN"`
+	Backends     []struct {
+		Group string `json:"group"`
+	} `json:"backends"`
+}
+
+func runLBDescribe(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	lbName := args[0]
+	projectID := viper.GetString("GCP_PROJECT_ID")
+	if projectID == "" {
+		return fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+	names := lbResourceNames(lbName)
+
+	root := &treeNode{label: fmt.Sprintf("Load balancer %s (project %s)", lbName, projectID)}
+
+	var fr lbForwardingRule
+	if err := gcloudJSON(&fr, "compute", "forwarding-rules", "describe", names.ForwardingRule,
+		"--global", "--project="+projectID); err != nil {
+		root.add("forwarding rule %s: not found", names.ForwardingRule)
+	} else {
+		frNode := root.add("forwarding rule %s (%s %s:%s)",
+			names.ForwardingRule, fr.IPProtocol, fr.IPAddress, fr.PortRange)
+		proxyGroup := "target-http-proxies"
+		if strings.Contains(fr.Target, "/targetHttpsProxies/") {
+			proxyGroup = "target-https-proxies"
+		}
+
+		var proxy lbProxy
+		if err := gcloudJSON(&proxy, "compute", proxyGroup, "describe", resourceName(fr.Target),
+			"--global", "--project="+projectID); err != nil {
+			frNode.add("%s %s: not found", strings.TrimSuffix(proxyGroup, "-proxies"), resourceName(fr.Target))
+		} else {
+			proxyNode := frNode.add("%s %s", strings.TrimSuffix(proxyGroup, "-proxies"), resourceName(fr.Target))
+			for _, cert := range proxy.SSLCertificates {
+				proxyNode.add("certificate %s", resourceName(cert))
+			}
+			describeURLMap(proxyNode, projectID, resourceName(proxy.URLMap))
+		}
+	}
+
+	var fr6 lbForwardingRule
+	if err := gcloudJSON(&fr6, "compute", "forwarding-rules", "describe", names.ForwardingRuleIPv6,
+		"--global", "--project="+projectID); err == nil {
+		root.add("forwarding rule %s (%s [%s]:%s) -> %s",
+			names.ForwardingRuleIPv6, fr6.IPProtocol, fr6.IPAddress, fr6.PortRange, resourceName(fr6.Target))
+	}
+
+	fmt.Println()
+	root.print()
+	return nil
+}
+
+func describeURLMap(parent *treeNode, projectID, name string) {
+	m, err := exportURLMap(projectID, name)
+	if err != nil {
+		parent.add("url map %s: not found", name)
+		return
+	}
+
+	mapNode := parent.add("url map %s", name)
+	backends := map[string]*lbBackendService{}
+	for _, svc := range m.services() {
+		var bs lbBackendService
+		if err := gcloudJSON(&bs, "compute", "backend-services", "describe", svc,
+			"--global", "--project="+projectID); err != nil {
+			continue
+		}
+		backends[svc] = &bs
+	}
+
+	addBackend := func(node *treeNode, label, svc string) {
+		n := node.add("%s → %s", label, svc)
+		bs, ok := backends[svc]
+		if !ok {
+			n.add("backend service not found")
+			return
+		}
+		n.add("protocol %s, CDN %v", bs.Protocol, bs.EnableCDN)
+		for _, hc := range bs.HealthChecks {
+			n.add("health check %s", resourceName(hc))
+		}
+		for _, b := range bs.Backends {
+			n.add("backend %s", resourceName(b.Group))
+		}
+	}
+
+	if m.DefaultService != "" {
+		addBackend(mapNode, "default", resourceName(m.DefaultService))
+	}
+	for _, hr := range m.HostRules {
+		hostNode := mapNode.add("hosts %s (matcher %s)", strings.Join(hr.Hosts, ", "), hr.PathMatcher)
+		for _, pm := range m.PathMatchers {
+			if pm.Name != hr.PathMatcher {
+				continue
+			}
+			if pm.DefaultService != "" {
+				addBackend(hostNode, "default", resourceName(pm.DefaultService))
+			}
+			for _, rule := range pm.PathRules {
+				addBackend(hostNode, strings.Join(rule.Paths, ", "), resourceName(rule.Service))
+			}
+			for _, rule := range pm.RouteRules {
+				if rule.Service != "" || rule.RouteAction == nil {
+					addBackend(hostNode, rule.path(), resourceName(rule.Service))
+					continue
+				}
+				routeNode := hostNode.add("%s (priority %d, weighted)", rule.path(), rule.Priority)
+				for _, wb := range rule.RouteAction.WeightedBackendServices {
+					addBackend(routeNode, fmt.Sprintf("%d%%", wb.Weight), resourceName(wb.BackendService))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type managedZone struct {
+	Name        string   `json:"name"`
+	DNSName     string   `json:"dnsName"`
+	NameServers []string `json:"nameServers"`
+}
+
+// configureLBDNS creates or reuses a Cloud DNS managed zone and points every
+// configured host at the reserved addresses of the load balancer, with an A
+// record and, when ipv6 is set, an AAAA record.
+func configureLBDNS(cfg LoadBalancerConfig) error {
+	names := lbResourceNames(cfg.LBName)
+	ttl := cfg.DNS.TTL
+	if ttl == 0 {
+		ttl = 300
+	}
+
+	if err := runGcloud(lbDryRun, "services", "enable", "dns.googleapis.com",
+		"--project="+cfg.ProjectID); err != nil {
+		return fmt.Errorf("failed to enable Cloud DNS API: %w", err)
+	}
+
+	ipv4, err := lbIPAddress(cfg, names.Address, names.ForwardingRule, "203.0.113.10")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Load balancer IPv4: %s\n", ipv4)
+	var ipv6 string
+	if cfg.IPv6 {
+		ipv6, err = lbIPAddress(cfg, names.AddressIPv6, names.ForwardingRuleIPv6, "2001:db8::10")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  Load balancer IPv6: %s\n", ipv6)
+	}
+
+	zone, created, err := ensureManagedZone(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range cfg.Hosts {
+		fqdn := dnsFQDN(host)
+		if err := upsertRecord(cfg.ProjectID, zone.Name, fqdn, "A", ipv4, ttl); err != nil {
+			return err
+		}
+		if ipv6 == "" {
+			continue
+		}
+		if err := upsertRecord(cfg.ProjectID, zone.Name, fqdn, "AAAA", ipv6, ttl); err != nil {
+			return err
+		}
+	}
+
+	if created {
+		fmt.Println()
+		fmt.Printf("  Zone '%s' is new. Delegate %s to Cloud DNS by adding these\n", zone.Name, zone.DNSName)
+		fmt.Println("  NS records at your registrar or in the parent zone:")
+		for _, ns := range zone.NameServers {
+			fmt.Printf("    %s  NS  %s\n", zone.DNSName, ns)
+		}
+	}
+	return nil
+}
+
+// lbIPAddress returns the reserved address, falling back to the address of
+// the forwarding rule, or placeholder in dry-run mode.
+func lbIPAddress(cfg LoadBalancerConfig, address, rule, placeholder string) (string, error) {
+	if lbDryRun {
+		return placeholder, nil
+	}
+	ip := lookupAddress(cfg.ProjectID, address)
+	if ip == "" {
+		var fr lbForwardingRule
+		if err := gcloudJSON(&fr, "compute", "forwarding-rules", "describe", rule,
+			"--global", "--project="+cfg.ProjectID); err == nil {
+			ip = fr.IPAddress
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("could not determine the IP address of %s (%s)", cfg.LBName, rule)
+	}
+	return ip, nil
+}
+
+func lookupAddress(projectID, name string) string {
+	output, err := exec.Command("gcloud", "compute", "addresses", "describe", name,
+		"--global", "--project="+projectID, "--format=value(address)").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// ensureManagedZone returns the configured zone, or the existing zone with the
+// longest DNS name covering all hosts. A zone is only created for an explicit
+// domain, as the registrable domain of a host cannot be told from its labels.
+func ensureManagedZone(cfg LoadBalancerConfig) (managedZone, bool, error) {
+	var zones []managedZone
+	if !lbDryRun {
+		if err := gcloudJSON(&zones, "dns", "managed-zones", "list", "--project="+cfg.ProjectID); err != nil {
+			return managedZone{}, false, fmt.Errorf("failed to list managed zones: %w", err)
+		}
+	}
+
+	var match *managedZone
+	for i, z := range zones {
+		if cfg.DNS.Zone != "" {
+			if z.Name == cfg.DNS.Zone {
+				match = &zones[i]
+				break
+			}
+			continue
+		}
+		if zoneCoversHosts(z.DNSName, cfg.Hosts) && (match == nil || len(z.DNSName) > len(match.DNSName)) {
+			match = &zones[i]
+		}
+	}
+	if match != nil {
+		if !zoneCoversHosts(match.DNSName, cfg.Hosts) {
+			return managedZone{}, false, fmt.Errorf("managed zone %s (%s) does not cover all hosts",
+				match.Name, match.DNSName)
+		}
+		fmt.Printf("  ✓ Using managed zone '%s' (%s)\n", match.Name, match.DNSName)
+		return *match, false, nil
+	}
+
+	domain := cfg.DNS.Domain
+	if domain == "" {
+		if lbDryRun {
+			zone := managedZone{Name: cfg.DNS.Zone}
+			if zone.Name == "" {
+				zone.Name = "ZONE"
+			}
+			fmt.Printf("  [dry-run] Would use the managed zone covering %s\n", strings.Join(cfg.Hosts, ", "))
+			return zone, false, nil
+		}
+		if cfg.DNS.Zone != "" {
+			return managedZone{}, false, fmt.Errorf("managed zone %s not found; set dns.domain to create it",
+				cfg.DNS.Zone)
+		}
+		return managedZone{}, false, fmt.Errorf("no managed zone covers %s; set dns.zone to an existing zone "+
+			"or dns.domain to create one", strings.Join(cfg.Hosts, ", "))
+	}
+	zone := managedZone{Name: cfg.DNS.Zone, DNSName: dnsFQDN(domain)}
+	if zone.Name == "" {
+		zone.Name = strings.ReplaceAll(strings.TrimSuffix(zone.DNSName, "."), ".", "-")
+	}
+	if !zoneCoversHosts(zone.DNSName, cfg.Hosts) {
+		return managedZone{}, false, fmt.Errorf("domain %s does not cover all hosts", domain)
+	}
+
+	fmt.Printf("  Creating managed zone '%s' (%s)...\n", zone.Name, zone.DNSName)
+	if err := runGcloud(lbDryRun, "dns", "managed-zones", "create", zone.Name,
+		"--dns-name="+zone.DNSName,
+		"--description=Managed by gcsetup for load balancer "+cfg.LBName,
+		"--project="+cfg.ProjectID,
+	); err != nil {
+		return managedZone{}, false, fmt.Errorf("failed to create managed zone: %w", err)
+	}
+
+	if !lbDryRun {
+		if err := gcloudJSON(&zone, "dns", "managed-zones", "describe", zone.Name,
+			"--project="+cfg.ProjectID); err != nil {
+			return managedZone{}, false, fmt.Errorf("failed to describe managed zone: %w", err)
+		}
+	}
+	fmt.Printf("  ✓ Managed zone '%s' created\n", zone.Name)
+	return zone, true, nil
+}
+
+func upsertRecord(projectID, zone, fqdn, recordType, value string, ttl int) error {
+	action := "create"
+	if !lbDryRun && gcloudExists("dns", "record-sets", "describe", fqdn,
+		"--type="+recordType, "--zone="+zone, "--project="+projectID) {
+		action = "update"
+	}
+
+	if err := runGcloud(lbDryRun, "dns", "record-sets", action, fqdn,
+		"--type="+recordType,
+		"--zone="+zone,
+		"--ttl="+strconv.Itoa(ttl),
+		"--rrdatas="+value,
+		"--project="+projectID,
+	); err != nil {
+		return fmt.Errorf("failed to %s %s record for %s: %w", action, recordType, fqdn, err)
+	}
+	fmt.Printf("  ✓ %s %s -> %s\n", recordType, fqdn, value)
+	return nil
+}
+
+func dnsFQDN(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+func zoneCoversHosts(dnsName string, hosts []string) bool {
+	for _, h := range hosts {
+		fqdn := dnsFQDN(h)
+		if fqdn != dnsName && !strings.HasSuffix(fqdn, "."+dnsName) {
+			return false
+		}
+	}
+	return true
+}
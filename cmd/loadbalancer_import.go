@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lbImportCmd = &cobra.Command{
+	Use:   "import <name>",
+	Short: "Import an existing load balancer into a gcsetup spec file",
+	Long: `Read an existing, possibly hand-built load balancer and write an equivalent
+spec file plus state entries, so subsequent changes go through gcsetup:
+  1. URL map (<name>-url-map, <name>, or --url-map)
+  2. HTTP(S) proxies pointing at the URL map
+  3. Global forwarding rules and reserved addresses pointing at the proxy
+  4. Backend services, health checks and serverless NEGs
+
+The spec is written to .gcsetup/loadbalancers/<name>.yaml unless --output is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLBImport,
+}
+
+var lbImportURLMap string
+var lbImportOutput string
+
+func init() {
+	loadbalancerCmd.AddCommand(lbImportCmd)
+	lbImportCmd.Flags().StringVar(&lbImportURLMap, "url-map", "", "Name of the URL map (default: detected)")
+	lbImportCmd.Flags().StringVarP(&lbImportOutput, "output", "o", "", "Path of the spec file to write")
+}
+
+type lbImportedProxy struct {
+	Name            string   `json:"name"`
+	URLMap          string   `json:"urlMap"`
+	SSLCertificates []string `json:"sslCertificates"`
+}
+
+type lbImportedForwardingRule struct {
+	Name      string `json:"name"`
+	IPAddress string `json:"IPAddress"`
+	Target    string `json:"target"`
+}
+
+type lbImportedAddress struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+type lbImportedHealthCheck struct {
+	HTTPHealthCheck struct {
+		Port int `json:"port"`
+	} `json:"httpHealthCheck"`
+}
+
+type lbImportedNEG struct {
+	CloudRun struct {
+		Service string `json:"service"`
+	} `json:"cloudRun"`
+}
+
+func runLBImport(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	lbName := args[0]
+	projectID := viper.GetString("GCP_PROJECT_ID")
+	if projectID == "" {
+		return fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+
+	output := lbImportOutput
+	if output == "" {
+		output = filepath.Join(stateDir, "loadbalancers", lbName+".yaml")
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Importing Load Balancer '%s'\n", lbName)
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	urlMapName := lbImportURLMap
+	if urlMapName == "" {
+		for _, candidate := range []string{lbResourceNames(lbName).URLMap, lbName} {
+			if gcloudExists("compute", "url-maps", "describe", candidate,
+				"--global", "--project="+projectID) {
+				urlMapName = candidate
+				break
+			}
+		}
+		if urlMapName == "" {
+			return fmt.Errorf("no URL map found for %s, pass --url-map", lbName)
+		}
+	}
+
+	m, err := exportURLMap(projectID, urlMapName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  ✓ URL map '%s'\n", urlMapName)
+
+	cfg := &LoadBalancerConfig{
+		ProjectID: projectID,
+		LBName:    lbName,
+		Network:   "default",
+	}
+	lbState := &LoadBalancerState{
+		Spec:       output,
+		URLMap:     urlMapName,
+		ImportedAt: time.Now().Format(time.RFC3339),
+	}
+
+	proxy, ssl, err := findImportProxy(projectID, urlMapName)
+	if err != nil {
+		return err
+	}
+	if proxy != nil {
+		fmt.Printf("  ✓ Proxy '%s'\n", proxy.Name)
+		lbState.Proxy = proxy.Name
+		cfg.UseSSL = ssl
+		var certs []string
+		for _, c := range proxy.SSLCertificates {
+			certs = append(certs, resourceName(c))
+		}
+		cfg.SSLCertificate = strings.Join(certs, ",")
+
+		if err := importForwardingRules(projectID, proxy.Name, cfg, lbState); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("  ⚠ No proxy uses the URL map")
+	}
+
+	if err := importServices(projectID, m, cfg, lbState); err != nil {
+		return err
+	}
+
+	if err := validateLBSpec(cfg); err != nil {
+		return fmt.Errorf("imported spec is invalid: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("failed to create spec directory: %w", err)
+	}
+	if err := writeLBSpec(output, cfg); err != nil {
+		return fmt.Errorf("failed to write spec file: %w", err)
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if st.LoadBalancers == nil {
+		st.LoadBalancers = map[string]*LoadBalancerState{}
+	}
+	st.LoadBalancers[lbName] = lbState
+	if err := saveState(st); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Println("  Load Balancer Imported!")
+	fmt.Println("==============================================")
+	fmt.Println()
+	fmt.Printf("Spec file:  %s\n", output)
+	fmt.Printf("State file: %s\n", statePath())
+	fmt.Println("Next steps:")
+	fmt.Println("  1. Review the spec file and commit it together with the state file")
+	fmt.Printf("  2. Apply changes with: gcsetup loadbalancer setup --spec %s\n", output)
+
+	return nil
+}
+
+// findImportProxy returns the proxy in front of the URL map, preferring HTTPS.
+func findImportProxy(projectID, urlMapName string) (*lbImportedProxy, bool, error) {
+	for _, group := range []string{"target-https-proxies", "target-http-proxies"} {
+		var proxies []lbImportedProxy
+		if err := gcloudJSON(&proxies, "compute", group, "list", "--global",
+			"--project="+projectID); err != nil {
+			return nil, false, fmt.Errorf("failed to list %s: %w", group, err)
+		}
+		for i := range proxies {
+			if resourceName(proxies[i].URLMap) == urlMapName {
+				return &proxies[i], group == "target-https-proxies", nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// importForwardingRules records the forwarding rules that use the proxy and
+// their reserved addresses. A rule on an IPv6 address turns on ipv6.
+func importForwardingRules(projectID, proxyName string, cfg *LoadBalancerConfig, lbState *LoadBalancerState) error {
+	var rules []lbImportedForwardingRule
+	if err := gcloudJSON(&rules, "compute", "forwarding-rules", "list", "--global",
+		"--project="+projectID); err != nil {
+		return fmt.Errorf("failed to list forwarding rules: %w", err)
+	}
+	var addresses []lbImportedAddress
+	if err := gcloudJSON(&addresses, "compute", "addresses", "list", "--global",
+		"--project="+projectID); err != nil {
+		return fmt.Errorf("failed to list addresses: %w", err)
+	}
+
+	found := false
+	for _, rule := range rules {
+		if resourceName(rule.Target) != proxyName {
+			continue
+		}
+		ipv6 := strings.Contains(rule.IPAddress, ":")
+		if (ipv6 && lbState.ForwardingRuleIPv6 != "") || (!ipv6 && lbState.ForwardingRule != "") {
+			fmt.Printf("  ⚠ Ignoring additional forwarding rule '%s' (%s)\n", rule.Name, rule.IPAddress)
+			continue
+		}
+		found = true
+		fmt.Printf("  ✓ Forwarding rule '%s' (%s)\n", rule.Name, rule.IPAddress)
+		address := ""
+		for _, a := range addresses {
+			if a.Address == rule.IPAddress {
+				fmt.Printf("  ✓ Address '%s'\n", a.Name)
+				address = a.Name
+				break
+			}
+		}
+		if address == "" {
+			fmt.Printf("  ⚠ Forwarding rule '%s' uses an ephemeral IP address\n", rule.Name)
+		}
+		if ipv6 {
+			cfg.IPv6 = true
+			lbState.ForwardingRuleIPv6 = rule.Name
+			lbState.AddressIPv6 = address
+		} else {
+			lbState.ForwardingRule = rule.Name
+			lbState.Address = address
+		}
+	}
+	if !found {
+		fmt.Println("  ⚠ No forwarding rule uses the proxy")
+	}
+	return nil
+}
+
+// importServices turns the backend services and rules of the URL map into
+// spec services and routes. The default service comes first, as setup uses
+// the first service as default.
+func importServices(projectID string, m *urlMap, cfg *LoadBalancerConfig, lbState *LoadBalancerState) error {
+	if len(m.PathMatchers) > 1 {
+		fmt.Println("  ⚠ URL map has several path matchers; host-specific rules are merged into one spec")
+	}
+
+	byBackend := map[string]*LoadBalancerService{}
+	var order []string
+	for _, backend := range m.services() {
+		svc, err := importService(projectID, backend, lbState)
+		if err != nil {
+			return err
+		}
+		if cfg.HealthCheckPort == 0 && svc.Port != 0 && len(svc.NEGs) == 0 {
+			cfg.HealthCheckPort = svc.Port
+		}
+		byBackend[backend] = svc
+		order = append(order, backend)
+	}
+
+	for _, pm := range m.PathMatchers {
+		for _, rule := range pm.PathRules {
+			if svc, ok := byBackend[resourceName(rule.Service)]; ok {
+				svc.Paths = append(svc.Paths, rule.Paths...)
+			}
+		}
+		for _, rule := range pm.RouteRules {
+			if rule.path() == "" {
+				fmt.Printf("  ⚠ Route rule with priority %d uses matches gcsetup cannot express, skipped\n",
+					rule.Priority)
+				continue
+			}
+			if rule.Service != "" {
+				if svc, ok := byBackend[resourceName(rule.Service)]; ok {
+					svc.Paths = append(svc.Paths, rule.path())
+				}
+				continue
+			}
+			if rule.RouteAction == nil {
+				continue
+			}
+			route := LoadBalancerRoute{Path: rule.path()}
+			var weights []int
+			for _, wb := range rule.RouteAction.WeightedBackendServices {
+				svc, ok := byBackend[resourceName(wb.BackendService)]
+				if !ok {
+					return fmt.Errorf("route %s sends traffic to %s, which is not a backend service of the URL map",
+						rule.path(), resourceName(wb.BackendService))
+				}
+				route.Backends = append(route.Backends, WeightedBackend{Service: svc.Name})
+				weights = append(weights, wb.Weight)
+			}
+			percents, err := percentWeights(weights)
+			if err != nil {
+				return fmt.Errorf("route %s: %w", rule.path(), err)
+			}
+			for i := range route.Backends {
+				route.Backends[i].Weight = percents[i]
+			}
+			if !slices.Equal(percents, weights) {
+				fmt.Printf("  ⚠ Route %s weights %v imported as percentages %v\n", rule.path(), weights, percents)
+			}
+			cfg.Routes = append(cfg.Routes, route)
+		}
+	}
+	for _, hr := range m.HostRules {
+		for _, h := range hr.Hosts {
+			if h != "*" {
+				cfg.Hosts = append(cfg.Hosts, h)
+			}
+		}
+	}
+
+	for _, backend := range order {
+		svc := byBackend[backend]
+		if len(svc.Paths) > 0 {
+			svc.Path, svc.Paths = svc.Paths[0], svc.Paths[1:]
+		}
+		cfg.Services = append(cfg.Services, *svc)
+	}
+	if cfg.HealthCheckPort == 0 {
+		cfg.HealthCheckPort = 8080
+	}
+	return nil
+}
+
+func importService(projectID, backend string, lbState *LoadBalancerState) (*LoadBalancerService, error) {
+	var bs lbBackendService
+	if err := gcloudJSON(&bs, "compute", "backend-services", "describe", backend,
+		"--global", "--project="+projectID); err != nil {
+		return nil, fmt.Errorf("failed to describe backend service %s: %w", backend, err)
+	}
+	fmt.Printf("  ✓ Backend service '%s'\n", backend)
+	lbState.BackendServices = append(lbState.BackendServices, backend)
+
+	svc := &LoadBalancerService{
+		Name:     trimBackendSuffix(backend),
+		Protocol: bs.Protocol,
+	}
+	if backendName(svc.Name) != backend {
+		svc.Backend = backend
+	}
+
+	if len(bs.HealthChecks) > 0 {
+		svc.HealthCheck = resourceName(bs.HealthChecks[0])
+		var hc lbImportedHealthCheck
+		if err := gcloudJSON(&hc, "compute", "health-checks", "describe", svc.HealthCheck,
+			"--global", "--project="+projectID); err == nil {
+			svc.Port = hc.HTTPHealthCheck.Port
+		}
+	}
+
+	for _, b := range bs.Backends {
+		if !strings.Contains(b.Group, "/networkEndpointGroups/") {
+			fmt.Printf("    ⚠ Backend '%s' is not a serverless NEG and is left unmanaged\n", resourceName(b.Group))
+			continue
+		}
+		neg := LoadBalancerNEG{Name: resourceName(b.Group), Region: urlSegmentAfter(b.Group, "regions")}
+		var described lbImportedNEG
+		if err := gcloudJSON(&described, "compute", "network-endpoint-groups", "describe", neg.Name,
+			"--region="+neg.Region, "--project="+projectID); err == nil {
+			neg.CloudRunService = described.CloudRun.Service
+		}
+		fmt.Printf("    ✓ NEG '%s' (%s)\n", neg.Name, neg.Region)
+		svc.NEGs = append(svc.NEGs, neg)
+		lbState.NetworkEndpointGroups = append(lbState.NetworkEndpointGroups, neg.Name)
+	}
+	return svc, nil
+}
+
+// urlSegmentAfter returns the path segment following key in a resource URL.
+func urlSegmentAfter(url, key string) string {
+	parts := strings.Split(url, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == key {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// percentWeights scales URL map weights, which may add up to any total, to
+// percentages adding up to 100. Rounding remainders go to the backends with
+// the largest fractions, earlier backends first.
+func percentWeights(weights []int) ([]int, error) {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("weights add up to 0")
+	}
+	percents := make([]int, len(weights))
+	order := make([]int, len(weights))
+	sum := 0
+	for i, w := range weights {
+		percents[i] = w * 100 / total
+		sum += percents[i]
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]]*100%total > weights[order[b]]*100%total
+	})
+	for i := 0; sum < 100; i++ {
+		percents[order[i]]++
+		sum++
+	}
+	return percents, nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPercentWeights(t *testing.T) {
+	tests := []struct {
+		weights []int
+		want    []int
+		wantErr bool
+	}{
+		{weights: []int{90, 10}, want: []int{90, 10}},
+		{weights: []int{900, 100}, want: []int{90, 10}},
+		{weights: []int{1, 9}, want: []int{10, 90}},
+		{weights: []int{1, 1, 1}, want: []int{34, 33, 33}},
+		{weights: []int{1, 2}, want: []int{33, 67}},
+		{weights: []int{1000}, want: []int{100}},
+		{weights: []int{0, 5}, want: []int{0, 100}},
+		{weights: []int{0, 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := percentWeights(tt.weights)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("percentWeights(%v) = %v, want an error", tt.weights, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("percentWeights(%v) = %v, %v, want %v", tt.weights, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	lbRequestCountMetric = "loadbalancing.googleapis.com/https/request_count"
+	lbLatencyMetric      = "loadbalancing.googleapis.com/https/backend_latencies"
+)
+
+type dashboard struct {
+	Name         string            `json:"name,omitempty"`
+	Etag         string            `json:"etag,omitempty"`
+	DisplayName  string            `json:"displayName"`
+	MosaicLayout mosaicLayout      `json:"mosaicLayout"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+type mosaicLayout struct {
+	Columns int             `json:"columns"`
+	Tiles   []dashboardTile `json:"tiles"`
+}
+
+type dashboardTile struct {
+	XPos   int             `json:"xPos"`
+	YPos   int             `json:"yPos"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Widget dashboardWidget `json:"widget"`
+}
+
+type dashboardWidget struct {
+	Title   string  `json:"title"`
+	XYChart xyChart `json:"xyChart"`
+}
+
+type xyChart struct {
+	DataSets []chartDataSet `json:"dataSets"`
+}
+
+type chartDataSet struct {
+	PlotType        string          `json:"plotType"`
+	TimeSeriesQuery timeSeriesQuery `json:"timeSeriesQuery"`
+}
+
+type timeSeriesQuery struct {
+	TimeSeriesFilter      *timeSeriesFilter      `json:"timeSeriesFilter,omitempty"`
+	TimeSeriesFilterRatio *timeSeriesFilterRatio `json:"timeSeriesFilterRatio,omitempty"`
+}
+
+type timeSeriesFilter struct {
+	Filter      string      `json:"filter"`
+	Aggregation aggregation `json:"aggregation"`
+}
+
+type timeSeriesFilterRatio struct {
+	Numerator   timeSeriesFilter `json:"numerator"`
+	Denominator timeSeriesFilter `json:"denominator"`
+}
+
+type aggregation struct {
+	AlignmentPeriod    string   `json:"alignmentPeriod"`
+	PerSeriesAligner   string   `json:"perSeriesAligner"`
+	CrossSeriesReducer string   `json:"crossSeriesReducer,omitempty"`
+	GroupByFields      []string `json:"groupByFields,omitempty"`
+}
+
+type alertPolicy struct {
+	Name                 string            `json:"name,omitempty"`
+	DisplayName          string            `json:"displayName"`
+	Combiner             string            `json:"combiner"`
+	Conditions           []alertCondition  `json:"conditions"`
+	NotificationChannels []string          `json:"notificationChannels,omitempty"`
+	UserLabels           map[string]string `json:"userLabels,omitempty"`
+}
+
+type alertCondition struct {
+	DisplayName        string             `json:"displayName"`
+	ConditionThreshold conditionThreshold `json:"conditionThreshold"`
+}
+
+type conditionThreshold struct {
+	Filter                  string        `json:"filter"`
+	Aggregations            []aggregation `json:"aggregations"`
+	DenominatorFilter       string        `json:"denominatorFilter,omitempty"`
+	DenominatorAggregations []aggregation `json:"denominatorAggregations,omitempty"`
+	Comparison              string        `json:"comparison"`
+	ThresholdValue          float64       `json:"thresholdValue"`
+	Duration                string        `json:"duration"`
+}
+
+// lbBackendFilter selects the load balancer metrics of one backend service.
+func lbBackendFilter(metric, urlMap, backend string) string {
+	return fmt.Sprintf(`metric.type="%s" AND resource.type="https_lb_rule" `+
+		`AND resource.label.url_map_name="%s" AND resource.label.backend_target_name="%s"`,
+		metric, urlMap, backend)
+}
+
+func lbMonitoringLabels(cfg LoadBalancerConfig) map[string]string {
+	return map[string]string{"managed-by": "gcsetup", "load-balancer": cfg.LBName}
+}
+
+// lbDashboard builds a dashboard with one row per backend service: request
+// rate by response class, 5xx ratio and p95 latency.
+func lbDashboard(cfg LoadBalancerConfig) dashboard {
+	urlMap := lbResourceNames(cfg.LBName).URLMap
+	d := dashboard{
+		DisplayName:  fmt.Sprintf("gcsetup: %s", cfg.LBName),
+		MosaicLayout: mosaicLayout{Columns: 12},
+		Labels:       map[string]string{"gcsetup": ""},
+	}
+
+	rate := aggregation{AlignmentPeriod: "60s", PerSeriesAligner: "ALIGN_RATE", CrossSeriesReducer: "REDUCE_SUM"}
+	for row, svc := range cfg.Services {
+		backend := svc.backend()
+		requests := lbBackendFilter(lbRequestCountMetric, urlMap, backend)
+		errors := requests + " AND metric.label.response_code_class=500"
+		byClass := rate
+		byClass.GroupByFields = []string{"metric.label.response_code_class"}
+
+		widgets := []dashboardWidget{
+			{
+				Title: fmt.Sprintf("%s - requests/s by response class", backend),
+				XYChart: xyChart{DataSets: []chartDataSet{{
+					PlotType: "STACKED_AREA",
+					TimeSeriesQuery: timeSeriesQuery{TimeSeriesFilter: &timeSeriesFilter{
+						Filter: requests, Aggregation: byClass,
+					}},
+				}}},
+			},
+			{
+				Title: fmt.Sprintf("%s - 5xx ratio", backend),
+				XYChart: xyChart{DataSets: []chartDataSet{{
+					PlotType: "LINE",
+					TimeSeriesQuery: timeSeriesQuery{TimeSeriesFilterRatio: &timeSeriesFilterRatio{
+						Numerator:   timeSeriesFilter{Filter: errors, Aggregation: rate},
+						Denominator: timeSeriesFilter{Filter: requests, Aggregation: rate},
+					}},
+				}}},
+			},
+			{
+				Title: fmt.Sprintf("%s - p95 backend latency (ms)", backend),
+				XYChart: xyChart{DataSets: []chartDataSet{{
+					PlotType: "LINE",
+					TimeSeriesQuery: timeSeriesQuery{TimeSeriesFilter: &timeSeriesFilter{
+						Filter: lbBackendFilter(lbLatencyMetric, urlMap, backend),
+						Aggregation: aggregation{
+							AlignmentPeriod:    "60s",
+							PerSeriesAligner:   "ALIGN_DELTA",
+							CrossSeriesReducer: "REDUCE_PERCENTILE_95",
+						},
+					}},
+				}}},
+			},
+		}
+
+		for col, w := range widgets {
+			d.MosaicLayout.Tiles = append(d.MosaicLayout.Tiles, dashboardTile{
+				XPos: col * 4, YPos: row * 4, Width: 4, Height: 4, Widget: w,
+			})
+		}
+	}
+	return d
+}
+
+// lbAlertPolicies builds a 5xx ratio and a p95 latency policy per backend.
+func lbAlertPolicies(cfg LoadBalancerConfig) []alertPolicy {
+	urlMap := lbResourceNames(cfg.LBName).URLMap
+	errorRatio := cfg.Monitoring.ErrorRatio
+	if errorRatio == 0 {
+		errorRatio = 0.05
+	}
+	latency := cfg.Monitoring.LatencyP95Ms
+	if latency == 0 {
+		latency = 1000
+	}
+
+	rate := aggregation{AlignmentPeriod: "300s", PerSeriesAligner: "ALIGN_RATE", CrossSeriesReducer: "REDUCE_SUM"}
+
+	var policies []alertPolicy
+	for _, svc := range cfg.Services {
+		backend := svc.backend()
+		requests := lbBackendFilter(lbRequestCountMetric, urlMap, backend)
+		errors := requests + " AND metric.label.response_code_class=500"
+
+		policies = append(policies, alertPolicy{
+			DisplayName: fmt.Sprintf("gcsetup: %s %s 5xx ratio", cfg.LBName, backend),
+			Combiner:    "OR",
+			Conditions: []alertCondition{{
+				DisplayName: fmt.Sprintf("5xx ratio above %g", errorRatio),
+				ConditionThreshold: conditionThreshold{
+					Filter:                  errors,
+					Aggregations:            []aggregation{rate},
+					DenominatorFilter:       requests,
+					DenominatorAggregations: []aggregation{rate},
+					Comparison:              "COMPARISON_GT",
+					ThresholdValue:          errorRatio,
+					Duration:                "300s",
+				},
+			}},
+			NotificationChannels: cfg.Monitoring.NotificationChannels,
+			UserLabels:           lbMonitoringLabels(cfg),
+		})
+
+		policies = append(policies, alertPolicy{
+			DisplayName: fmt.Sprintf("gcsetup: %s %s p95 latency", cfg.LBName, backend),
+			Combiner:    "OR",
+			Conditions: []alertCondition{{
+				DisplayName: fmt.Sprintf("p95 latency above %dms", latency),
+				ConditionThreshold: conditionThreshold{
+					Filter: lbBackendFilter(lbLatencyMetric, urlMap, backend),
+					Aggregations: []aggregation{{
+						AlignmentPeriod:    "300s",
+						PerSeriesAligner:   "ALIGN_DELTA",
+						CrossSeriesReducer: "REDUCE_PERCENTILE_95",
+					}},
+					Comparison:     "COMPARISON_GT",
+					ThresholdValue: float64(latency),
+					Duration:       "300s",
+				},
+			}},
+			NotificationChannels: cfg.Monitoring.NotificationChannels,
+			UserLabels:           lbMonitoringLabels(cfg),
+		})
+	}
+	return policies
+}
+
+// configureLBMonitoring creates or updates the dashboard and alert policies,
+// matching existing ones by display name.
+func configureLBMonitoring(cfg LoadBalancerConfig) error {
+	if err := runGcloud(lbDryRun, "services", "enable", "monitoring.googleapis.com",
+		"--project="+cfg.ProjectID); err != nil {
+		return fmt.Errorf("failed to enable Cloud Monitoring API: %w", err)
+	}
+
+	d := lbDashboard(cfg)
+	var existingDashboards []dashboard
+	if !lbDryRun {
+		if err := gcloudJSON(&existingDashboards, "monitoring", "dashboards", "list",
+			"--project="+cfg.ProjectID); err != nil {
+			return fmt.Errorf("failed to list dashboards: %w", err)
+		}
+	}
+	args := []string{"monitoring", "dashboards", "create"}
+	for _, existing := range existingDashboards {
+		if existing.DisplayName == d.DisplayName {
+			d.Name, d.Etag = existing.Name, existing.Etag
+			args = []string{"monitoring", "dashboards", "update", existing.Name}
+		}
+	}
+	args = append(args, "--project="+cfg.ProjectID)
+	if err := applyMonitoringJSON(d, d.DisplayName, args, "--config="); err != nil {
+		return fmt.Errorf("failed to apply dashboard: %w", err)
+	}
+
+	var existingPolicies []alertPolicy
+	if !lbDryRun {
+		if err := gcloudJSON(&existingPolicies, "alpha", "monitoring", "policies", "list",
+			"--project="+cfg.ProjectID); err != nil {
+			return fmt.Errorf("failed to list alert policies: %w", err)
+		}
+	}
+	for _, p := range lbAlertPolicies(cfg) {
+		args := []string{"alpha", "monitoring", "policies", "create"}
+		for _, existing := range existingPolicies {
+			if existing.DisplayName == p.DisplayName {
+				args = []string{"alpha", "monitoring", "policies", "update", existing.Name}
+			}
+		}
+		args = append(args, "--project="+cfg.ProjectID)
+		if err := applyMonitoringJSON(p, p.DisplayName, args, "--policy="); err != nil {
+			return fmt.Errorf("failed to apply alert policy %s: %w", p.DisplayName, err)
+		}
+	}
+
+	if len(cfg.Monitoring.NotificationChannels) == 0 {
+		fmt.Println("  💡 Tip: add notificationChannels to the monitoring section of the spec")
+		fmt.Println("     to get notified when an alert fires")
+	}
+	return nil
+}
+
+func applyMonitoringJSON(v any, displayName string, args []string, flag string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if lbDryRun {
+		pretty, _ := json.MarshalIndent(v, "    ", "  ")
+		fmt.Printf("  [dry-run] gcloud %s %s\n", strings.Join(args, " "), flag+"<json>")
+		fmt.Printf("    %s\n", pretty)
+		return nil
+	}
+
+	if err := runGcloud(false, append(args, flag+string(data))...); err != nil {
+		return err
+	}
+	fmt.Printf("  ✓ %s\n", displayName)
+	return nil
+}
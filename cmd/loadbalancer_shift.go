@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lbShiftCmd = &cobra.Command{
+	Use:   "shift <name>",
+	Short: "Progressively shift the traffic of a path to another service",
+	Long: `Shift the traffic of a path from one backend service to another in steps,
+using weighted backend services in the URL map route rules.
+
+Between steps the command waits for --interval, or asks for confirmation
+when no interval is given.
+
+Example:
+  gcsetup loadbalancer shift gcloud-lb --path /api/* --to api-v2 --steps 10,50,100 --interval 10m`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLBShift,
+}
+
+var (
+	lbShiftPath     string
+	lbShiftFrom     string
+	lbShiftTo       string
+	lbShiftSteps    []int
+	lbShiftInterval time.Duration
+)
+
+func init() {
+	loadbalancerCmd.AddCommand(lbShiftCmd)
+	lbShiftCmd.Flags().StringVar(&lbShiftPath, "path", "", "Path whose traffic is shifted (e.g., /api/*)")
+	lbShiftCmd.Flags().StringVar(&lbShiftFrom, "from", "", "Service currently serving the path (default: detected)")
+	lbShiftCmd.Flags().StringVar(&lbShiftTo, "to", "", "Service receiving the traffic")
+	lbShiftCmd.Flags().IntSliceVar(&lbShiftSteps, "steps", []int{10, 50, 100}, "Percentages sent to --to per step")
+	lbShiftCmd.Flags().DurationVar(&lbShiftInterval, "interval", 0, "Pause between steps")
+	lbShiftCmd.Flags().StringVar(&lbSpecFile, "spec", "",
+		"Spec file to keep in sync with the new weights (default: from state file)")
+	_ = lbShiftCmd.MarkFlagRequired("path")
+	_ = lbShiftCmd.MarkFlagRequired("to")
+}
+
+func runLBShift(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	lbName := args[0]
+	projectID := viper.GetString("GCP_PROJECT_ID")
+	if projectID == "" {
+		return fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+
+	if err := validateTrafficSteps(lbShiftSteps); err != nil {
+		return fmt.Errorf("--%w", err)
+	}
+
+	cfg, err := lbSpecConfig(lbName)
+	if err != nil {
+		return err
+	}
+	names := lbResourceNames(lbName)
+	m, err := exportURLMap(projectID, names.URLMap)
+	if err != nil {
+		return err
+	}
+
+	from := lbShiftFrom
+	if from == "" {
+		from, err = detectShiftSource(m, cfg, lbShiftPath, lbShiftTo)
+		if err != nil {
+			return err
+		}
+	}
+	if from == lbShiftTo {
+		return fmt.Errorf("--from and --to must differ")
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Shifting %s on '%s'\n", lbShiftPath, lbName)
+	fmt.Println("==============================================")
+	fmt.Printf("  From:   %s\n", cfg.backendFor(from))
+	fmt.Printf("  To:     %s\n", cfg.backendFor(lbShiftTo))
+	fmt.Printf("  Steps:  %v\n", lbShiftSteps)
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	for i, step := range lbShiftSteps {
+		if i > 0 {
+			if lbShiftInterval > 0 {
+				fmt.Printf("  Waiting %s before the next step...\n", lbShiftInterval)
+				if !lbDryRun {
+					time.Sleep(lbShiftInterval)
+				}
+			} else if !lbNonInteractive && !promptConfirm(fmt.Sprintf("Continue to %d%%?", step)) {
+				fmt.Println("Shift stopped; traffic stays at the current weights.")
+				return nil
+			}
+		}
+
+		backends := []WeightedBackend{
+			{Service: from, Weight: 100 - step},
+			{Service: lbShiftTo, Weight: step},
+		}
+
+		fmt.Printf("Step %d/%d: %s\n", i+1, len(lbShiftSteps), cfg.formatWeights(backends))
+		fmt.Println("----------------------------------------------")
+
+		// Re-export for every step so concurrent edits are not overwritten.
+		if i > 0 && !lbDryRun {
+			if m, err = exportURLMap(projectID, names.URLMap); err != nil {
+				return err
+			}
+		}
+		m.setWeightedRoute(lbShiftPath, cfg.weightedBackendServices(projectID, backends))
+		if err := importURLMap(projectID, m); err != nil {
+			return err
+		}
+		fmt.Printf("  ✓ %d%% of %s now served by %s\n", step, lbShiftPath, cfg.backendFor(lbShiftTo))
+
+		syncLBSpec(lbName, func(cfg *LoadBalancerConfig) {
+			setSpecRoute(cfg, lbShiftPath, backends)
+		})
+		fmt.Println()
+	}
+
+	fmt.Println("==============================================")
+	fmt.Println("  Traffic Shift Complete!")
+	fmt.Println("==============================================")
+	return nil
+}
+
+// detectShiftSource returns the service the path is currently routed to,
+// ignoring the target service of a shift already in progress.
+func detectShiftSource(m *urlMap, cfg *LoadBalancerConfig, path, to string) (string, error) {
+	if backend := m.serviceForPath(path); backend != "" {
+		return cfg.serviceFor(backend), nil
+	}
+
+	if r := m.route(path); r != nil && r.RouteAction != nil {
+		var candidates []string
+		for _, wb := range r.RouteAction.WeightedBackendServices {
+			if svc := cfg.serviceFor(resourceName(wb.BackendService)); svc != to {
+				candidates = append(candidates, svc)
+			}
+		}
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+	}
+
+	return "", fmt.Errorf("could not detect the service serving %s, pass --from", path)
+}
+
+func setSpecRoute(cfg *LoadBalancerConfig, routePath string, backends []WeightedBackend) {
+	for _, b := range backends {
+		cfg.ensureSpecService(b.Service)
+	}
+
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Path == routePath {
+			cfg.Routes[i].Backends = backends
+			return
+		}
+	}
+	cfg.Routes = append(cfg.Routes, LoadBalancerRoute{Path: routePath, Backends: backends})
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// loadLBSpec reads a load balancer spec file and fills in the defaults the
+// interactive setup would use.
+func loadLBSpec(path string) (*LoadBalancerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file: %w", err)
+	}
+
+	cfg := &LoadBalancerConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse spec file %s: %w", path, err)
+	}
+
+	if cfg.LBName == "" {
+		cfg.LBName = "gcloud-lb"
+	}
+	if cfg.Network == "" {
+		cfg.Network = "default"
+	}
+	if cfg.HealthCheckPort == 0 {
+		cfg.HealthCheckPort = 8080
+	}
+	if cfg.Logging != nil && !cfg.Logging.Disabled && cfg.Logging.SampleRate == 0 {
+		cfg.Logging.SampleRate = 1.0
+	}
+	for i := range cfg.Services {
+		svc := &cfg.Services[i]
+		if svc.Protocol == "" {
+			svc.Protocol = "HTTP"
+		}
+		if svc.Port == 0 {
+			svc.Port = 8080
+		}
+		if svc.HealthCheck == "" {
+			svc.HealthCheck = fmt.Sprintf("%s-hc", svc.Name)
+		}
+	}
+
+	if err := validateLBSpec(cfg); err != nil {
+		return nil, fmt.Errorf("invalid spec file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func validateLBSpec(cfg *LoadBalancerConfig) error {
+	if len(cfg.Services) == 0 {
+		return fmt.Errorf("at least one service is required")
+	}
+	if cfg.UseSSL && cfg.SSLCertificate == "" {
+		return fmt.Errorf("sslCertificate is required when ssl is enabled")
+	}
+	if cfg.DNS != nil && len(cfg.Hosts) == 0 {
+		return fmt.Errorf("hosts are required when dns is enabled")
+	}
+
+	services := map[string]bool{}
+	for _, svc := range cfg.Services {
+		if svc.Name == "" {
+			return fmt.Errorf("service name is required")
+		}
+		services[svc.Name] = true
+	}
+
+	for _, route := range cfg.Routes {
+		if route.Path == "" {
+			return fmt.Errorf("route path is required")
+		}
+		if err := validateWeights(route.Backends); err != nil {
+			return fmt.Errorf("route %s: %w", route.Path, err)
+		}
+		for _, b := range route.Backends {
+			if !services[b.Service] {
+				return fmt.Errorf("route %s: unknown service %s", route.Path, b.Service)
+			}
+		}
+	}
+	return nil
+}
+
+func validateWeights(backends []WeightedBackend) error {
+	if len(backends) == 0 {
+		return fmt.Errorf("at least one backend is required")
+	}
+	total := 0
+	for _, b := range backends {
+		if b.Weight < 0 || b.Weight > 100 {
+			return fmt.Errorf("weight of %s must be between 0 and 100", b.Service)
+		}
+		total += b.Weight
+	}
+	if total != 100 {
+		return fmt.Errorf("weights must add up to 100, got %d", total)
+	}
+	return nil
+}
+
+func writeLBSpec(path string, cfg *LoadBalancerConfig) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// lbSpecPath returns the spec file of a load balancer: the --spec flag, or
+// the spec recorded in the state file by setup or import.
+func lbSpecPath(lbName string) string {
+	if lbSpecFile != "" {
+		return lbSpecFile
+	}
+	st, err := loadState()
+	if err != nil {
+		return ""
+	}
+	if lb, ok := st.LoadBalancers[lbName]; ok {
+		return lb.Spec
+	}
+	return ""
+}
+
+// lbSpecConfig loads the spec of a load balancer to resolve service and
+// backend names. Without a spec, every name follows the naming scheme.
+func lbSpecConfig(lbName string) (*LoadBalancerConfig, error) {
+	path := lbSpecPath(lbName)
+	if path == "" {
+		return &LoadBalancerConfig{LBName: lbName}, nil
+	}
+	return loadLBSpec(path)
+}
+
+func recordLBSpec(lbName, path string) error {
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if st.LoadBalancers == nil {
+		st.LoadBalancers = map[string]*LoadBalancerState{}
+	}
+	lb, ok := st.LoadBalancers[lbName]
+	if !ok {
+		lb = &LoadBalancerState{}
+		st.LoadBalancers[lbName] = lb
+	}
+	lb.Spec = path
+	return saveState(st)
+}
+
+// syncLBSpec applies a change made to the live load balancer to its spec
+// file, if it has one, so that the next setup does not revert it.
+func syncLBSpec(lbName string, mutate func(cfg *LoadBalancerConfig)) {
+	path := lbSpecPath(lbName)
+	if path == "" || lbDryRun {
+		return
+	}
+
+	cfg, err := loadLBSpec(path)
+	if err == nil {
+		mutate(cfg)
+		err = writeLBSpec(path, cfg)
+	}
+	if err != nil {
+		fmt.Printf("  ⚠ Could not update spec file %s: %v\n", path, err)
+		return
+	}
+	fmt.Printf("  ✓ Spec file %s updated\n", path)
+}
+
+// ensureSpecService adds a bare service entry for name unless one exists.
+func (cfg *LoadBalancerConfig) ensureSpecService(name string) {
+	for _, svc := range cfg.Services {
+		if svc.Name == name {
+			return
+		}
+	}
+	cfg.Services = append(cfg.Services, LoadBalancerService{Name: name})
+}
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var lbUpdateCmd = &cobra.Command{
+	Use:   "update <name>",
+	Short: "Add or remove services and path rules of an existing load balancer",
+	Long: `Update an existing load balancer created by gcsetup:
+  - Add services (health check, backend service and path rule)
+  - Remove services (path rules, backend service and health check)
+  - Add or remove individual path rules
+  - Swap the SSL certificates of the HTTPS proxy
+
+Example:
+  gcsetup loadbalancer update gcloud-lb \
+    --add-service api=/api/* \
+    --remove-path /legacy/* \
+    --certificate new-cert`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLBUpdate,
+}
+
+var (
+	lbAddServices     []string
+	lbRemoveServices  []string
+	lbAddPaths        []string
+	lbRemovePaths     []string
+	lbCertificates    []string
+	lbHealthCheckPort int
+)
+
+func init() {
+	loadbalancerCmd.AddCommand(lbUpdateCmd)
+	lbUpdateCmd.Flags().StringArrayVar(&lbAddServices, "add-service", nil,
+		"Add a service as name=path (repeatable)")
+	lbUpdateCmd.Flags().StringArrayVar(&lbRemoveServices, "remove-service", nil,
+		"Remove a service and its path rules (repeatable)")
+	lbUpdateCmd.Flags().StringArrayVar(&lbAddPaths, "add-path", nil,
+		"Route a path to an existing service as path=service (repeatable)")
+	lbUpdateCmd.Flags().StringArrayVar(&lbRemovePaths, "remove-path", nil,
+		"Remove a path rule (repeatable)")
+	lbUpdateCmd.Flags().StringSliceVar(&lbCertificates, "certificate", nil,
+		"Replace the SSL certificates of the HTTPS proxy")
+	lbUpdateCmd.Flags().IntVar(&lbHealthCheckPort, "health-check-port", 8080,
+		"Health check port for added services")
+	lbUpdateCmd.Flags().StringVar(&lbSpecFile, "spec", "",
+		"Spec file to keep in sync with the changes (default: from state file)")
+}
+
+func runLBUpdate(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	lbName := args[0]
+	projectID := viper.GetString("GCP_PROJECT_ID")
+	if projectID == "" {
+		return fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+
+	if len(lbAddServices)+len(lbRemoveServices)+len(lbAddPaths)+len(lbRemovePaths)+len(lbCertificates) == 0 {
+		return fmt.Errorf("nothing to update: pass --add-service, --remove-service, " +
+			"--add-path, --remove-path or --certificate")
+	}
+
+	var added []LoadBalancerService
+	for _, spec := range lbAddServices {
+		name, path, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid --add-service %q, expected name=path", spec)
+		}
+		added = append(added, LoadBalancerService{
+			Name:        name,
+			Protocol:    "HTTP",
+			Port:        lbHealthCheckPort,
+			Path:        path,
+			HealthCheck: fmt.Sprintf("%s-hc", name),
+		})
+	}
+
+	var paths []lbPathRule
+	for _, spec := range lbAddPaths {
+		path, svc, ok := strings.Cut(spec, "=")
+		if !ok || path == "" || svc == "" {
+			return fmt.Errorf("invalid --add-path %q, expected path=service", spec)
+		}
+		paths = append(paths, lbPathRule{Path: path, Service: svc})
+	}
+
+	cfg, err := lbSpecConfig(lbName)
+	if err != nil {
+		return err
+	}
+	for _, rule := range paths {
+		if err := checkPathService(projectID, cfg, added, rule.Service); err != nil {
+			return fmt.Errorf("invalid --add-path %s=%s: %w", rule.Path, rule.Service, err)
+		}
+	}
+	names := lbResourceNames(lbName)
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Updating Load Balancer '%s'\n", lbName)
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	m, err := exportURLMap(projectID, names.URLMap)
+	if err != nil {
+		return err
+	}
+
+	for _, svc := range lbRemoveServices {
+		if m.isDefaultService(cfg.backendFor(svc)) {
+			return fmt.Errorf("service %s is the default service of %s and cannot be removed", svc, names.URLMap)
+		}
+	}
+
+	if !lbNonInteractive {
+		if !promptConfirm("Proceed with load balancer update?") {
+			fmt.Println("Update cancelled.")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	if len(added) > 0 {
+		svcCfg := LoadBalancerConfig{
+			ProjectID:       projectID,
+			LBName:          lbName,
+			Services:        added,
+			HealthCheckPort: lbHealthCheckPort,
+			Logging:         cfg.Logging,
+		}
+		fmt.Println("Adding services...")
+		fmt.Println("----------------------------------------------")
+		if err := createHealthChecks(svcCfg); err != nil {
+			return err
+		}
+		if err := createBackendServices(svcCfg); err != nil {
+			return err
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Updating URL map...")
+	fmt.Println("----------------------------------------------")
+	for _, svc := range added {
+		fmt.Printf("  + %s -> %s\n", svc.Path, svc.backend())
+		m.setPathRule(svc.Path, backendServiceURL(projectID, svc.backend()))
+	}
+	for _, rule := range paths {
+		fmt.Printf("  + %s -> %s\n", rule.Path, cfg.backendFor(rule.Service))
+		m.setPathRule(rule.Path, backendServiceURL(projectID, cfg.backendFor(rule.Service)))
+	}
+	for _, path := range lbRemovePaths {
+		if !m.removePath(path) {
+			fmt.Printf("  ⚠ Path rule '%s' not found\n", path)
+			continue
+		}
+		fmt.Printf("  - %s\n", path)
+	}
+	for _, svc := range lbRemoveServices {
+		n := m.removeService(cfg.backendFor(svc))
+		fmt.Printf("  - %s (%d path rule(s))\n", cfg.backendFor(svc), n)
+	}
+	if err := importURLMap(projectID, m); err != nil {
+		return err
+	}
+	fmt.Printf("  ✓ URL map '%s' updated\n", names.URLMap)
+	fmt.Println()
+
+	if len(lbRemoveServices) > 0 {
+		fmt.Println("Removing services...")
+		fmt.Println("----------------------------------------------")
+		for _, svc := range lbRemoveServices {
+			if err := deleteBackendService(projectID, cfg.backendFor(svc)); err != nil {
+				return err
+			}
+		}
+		fmt.Println()
+	}
+
+	if len(lbCertificates) > 0 {
+		fmt.Println("Updating certificates...")
+		fmt.Println("----------------------------------------------")
+		if !lbDryRun && !gcloudExists("compute", "target-https-proxies", "describe", names.Proxy,
+			"--global", "--project="+projectID) {
+			return fmt.Errorf("HTTPS proxy '%s' not found; the load balancer does not use SSL", names.Proxy)
+		}
+		if err := runGcloud(lbDryRun, "compute", "target-https-proxies", "update", names.Proxy,
+			"--global",
+			"--ssl-certificates="+strings.Join(lbCertificates, ","),
+			"--project="+projectID,
+		); err != nil {
+			return fmt.Errorf("failed to update certificates: %w", err)
+		}
+		fmt.Printf("  ✓ Proxy '%s' now uses %s\n", names.Proxy, strings.Join(lbCertificates, ", "))
+		fmt.Println()
+	}
+
+	syncLBSpec(lbName, applyUpdateToSpec(added, paths))
+	fmt.Println()
+
+	fmt.Println("==============================================")
+	fmt.Println("  Load Balancer Update Complete!")
+	fmt.Println("==============================================")
+
+	return nil
+}
+
+// lbPathRule is a path routed to a service by --add-path.
+type lbPathRule struct {
+	Path    string
+	Service string
+}
+
+// checkPathService checks that a service a path is routed to is added by the
+// same update, listed in the spec or has a backend service.
+func checkPathService(projectID string, cfg *LoadBalancerConfig, added []LoadBalancerService, name string) error {
+	for _, svc := range added {
+		if svc.Name == name {
+			return nil
+		}
+	}
+	for _, svc := range cfg.Services {
+		if svc.Name == name {
+			return nil
+		}
+	}
+	if lbDryRun || gcloudExists("compute", "backend-services", "describe", cfg.backendFor(name),
+		"--global", "--project="+projectID) {
+		return nil
+	}
+	return fmt.Errorf("service %s not found; add it with --add-service", name)
+}
+
+// deleteBackendService deletes a backend service together with the health
+// checks it references.
+func deleteBackendService(projectID, backend string) error {
+	if lbDryRun {
+		return runGcloud(true, "compute", "backend-services", "delete", backend,
+			"--global", "--quiet", "--project="+projectID)
+	}
+
+	var bs struct {
+		HealthChecks []string `json:"healthChecks"`
+	}
+	if err := gcloudJSON(&bs, "compute", "backend-services", "describe", backend,
+		"--global", "--project="+projectID); err != nil {
+		fmt.Printf("  ✓ Backend service '%s' does not exist\n", backend)
+		return nil
+	}
+
+	if err := runGcloud(false, "compute", "backend-services", "delete", backend,
+		"--global", "--quiet", "--project="+projectID); err != nil {
+		return fmt.Errorf("failed to delete backend service %s: %w", backend, err)
+	}
+	fmt.Printf("  ✓ Backend service '%s' deleted\n", backend)
+
+	for _, hc := range bs.HealthChecks {
+		if err := runGcloud(false, "compute", "health-checks", "delete", resourceName(hc),
+			"--global", "--quiet", "--project="+projectID); err != nil {
+			fmt.Printf("  ⚠ Could not delete health check '%s' (may be in use): %v\n", resourceName(hc), err)
+			continue
+		}
+		fmt.Printf("  ✓ Health check '%s' deleted\n", resourceName(hc))
+	}
+	return nil
+}
+
+// removeSpecPath drops path from every service and route of the spec, as
+// the URL map serves each path from a single rule.
+func (cfg *LoadBalancerConfig) removeSpecPath(path string) {
+	for i := range cfg.Services {
+		svc := &cfg.Services[i]
+		if svc.Path == path {
+			svc.Path = ""
+		}
+		var paths []string
+		for _, p := range svc.Paths {
+			if p != path {
+				paths = append(paths, p)
+			}
+		}
+		svc.Paths = paths
+	}
+	var routes []LoadBalancerRoute
+	for _, r := range cfg.Routes {
+		if r.Path != path {
+			routes = append(routes, r)
+		}
+	}
+	cfg.Routes = routes
+}
+
+// applyUpdateToSpec mirrors the update flags onto a spec file.
+func applyUpdateToSpec(added []LoadBalancerService, paths []lbPathRule) func(cfg *LoadBalancerConfig) {
+	return func(cfg *LoadBalancerConfig) {
+		for _, svc := range added {
+			cfg.ensureSpecService(svc.Name)
+			if svc.Path != "" {
+				cfg.removeSpecPath(svc.Path)
+			}
+			for i := range cfg.Services {
+				if cfg.Services[i].Name == svc.Name {
+					cfg.Services[i].Path = svc.Path
+				}
+			}
+		}
+
+		for _, rule := range paths {
+			cfg.ensureSpecService(rule.Service)
+			cfg.removeSpecPath(rule.Path)
+			for i := range cfg.Services {
+				if cfg.Services[i].Name == rule.Service {
+					cfg.Services[i].Paths = append(cfg.Services[i].Paths, rule.Path)
+				}
+			}
+		}
+
+		for _, path := range lbRemovePaths {
+			cfg.removeSpecPath(path)
+		}
+
+		for _, name := range lbRemoveServices {
+			var services []LoadBalancerService
+			for _, svc := range cfg.Services {
+				if svc.Name != name {
+					services = append(services, svc)
+				}
+			}
+			cfg.Services = services
+
+			var routes []LoadBalancerRoute
+			for _, r := range cfg.Routes {
+				var backends []WeightedBackend
+				for _, b := range r.Backends {
+					if b.Service != name {
+						backends = append(backends, b)
+					}
+				}
+				r.Backends = backends
+				if validateWeights(backends) != nil {
+					fmt.Printf("  ⚠ Route %s dropped from spec: weights no longer add up to 100\n", r.Path)
+					continue
+				}
+				routes = append(routes, r)
+			}
+			cfg.Routes = routes
+		}
+
+		if len(lbCertificates) > 0 {
+			cfg.UseSSL = true
+			cfg.SSLCertificate = strings.Join(lbCertificates, ",")
+		}
+	}
+}
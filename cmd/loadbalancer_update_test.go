@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyUpdateToSpecMovesPaths(t *testing.T) {
+	cfg := &LoadBalancerConfig{
+		Services: []LoadBalancerService{
+			{Name: "web", Path: "/*"},
+			{Name: "api", Path: "/api/*", Paths: []string{"/v1/*"}},
+			{Name: "api-v2", Paths: []string{"/v1/*"}},
+		},
+		Routes: []LoadBalancerRoute{{Path: "/api/*", Backends: []WeightedBackend{
+			{Service: "api", Weight: 50}, {Service: "api-v2", Weight: 50},
+		}}},
+	}
+	applyUpdateToSpec(nil, []lbPathRule{
+		{Path: "/api/*", Service: "api-v2"},
+		{Path: "/v1/*", Service: "api-v2"},
+	})(cfg)
+
+	want := []LoadBalancerService{
+		{Name: "web", Path: "/*"},
+		{Name: "api"},
+		{Name: "api-v2", Paths: []string{"/api/*", "/v1/*"}},
+	}
+	if !reflect.DeepEqual(cfg.Services, want) {
+		t.Errorf("services = %+v, want %+v", cfg.Services, want)
+	}
+	if len(cfg.Routes) != 0 {
+		t.Errorf("routes = %+v, want none", cfg.Routes)
+	}
+}
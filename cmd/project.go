@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var projectCmd = &cobra.Command{
@@ -24,7 +25,8 @@ var projectCreateCmd = &cobra.Command{
   1. Project creation in GCP
   2. Enabling required APIs
   3. Creating service account with necessary roles
-  4. Setting up Workload Identity Federation for GitHub
+  4. Setting up Workload Identity Federation for GitHub (or GitLab, chosen like
+     gcsetup service does) and binding the service account
   5. Creating Artifact Registry repository`,
 	RunE: runProjectCreate,
 }
@@ -47,12 +49,26 @@ type ProjectConfig struct {
 	ProjectName              string
 	ServiceAccountName       string
 	ServiceAccountEmail      string
+	CIProvider               string
+	GitHubOrg                string
+	GitHubRepo               string
+	GitLabURL                string
+	GitLabProject            string
 	ArtifactRegistryName     string
 	ArtifactRegistryLocation string
 	WorkloadIdentityProvider string
 	ArtifactRegistryURL      string
 }
 
+// detectGitHubRepo returns the org and repository of the origin remote.
+func detectGitHubRepo() (org, repo string) {
+	output, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", ""
+	}
+	return parseGitRemote(strings.TrimSpace(string(output)))
+}
+
 func runProjectCreate(cmd *cobra.Command, args []string) error {
 	if err := checkGcloud(); err != nil {
 		return err
@@ -64,7 +80,11 @@ func runProjectCreate(cmd *cobra.Command, args []string) error {
 	fmt.Println("==============================================")
 	fmt.Println()
 
-	cfg := ProjectConfig{}
+	ciProvider, err := detectCIProvider()
+	if err != nil {
+		return err
+	}
+	cfg := ProjectConfig{CIProvider: ciProvider}
 
 	if !projectNonInteractive {
 		if err := interactiveProjectConfig(&cfg); err != nil {
@@ -73,17 +93,31 @@ func runProjectCreate(cmd *cobra.Command, args []string) error {
 	} else {
 		cfg.ProjectName = promptProject("Project Name", "my-project")
 		cfg.ProjectID = promptProject("Project ID", "my-project")
+		promptProjectRepository(&cfg)
 		cfg.ServiceAccountName = promptProject("Service Account Name", "github-actions")
 		cfg.ArtifactRegistryName = promptProject("Artifact Registry Name", "docker")
 		cfg.ArtifactRegistryLocation = promptProject("Artifact Registry Location", "us-central1")
 	}
 
+	if cfg.CIProvider == "gitlab" && cfg.GitLabProject == "" {
+		return fmt.Errorf("GitLab project is required for Workload Identity Federation")
+	}
+	if cfg.CIProvider != "gitlab" && (cfg.GitHubOrg == "" || cfg.GitHubRepo == "") {
+		return fmt.Errorf("GitHub organization and repository are required for Workload Identity Federation")
+	}
+	cfg.ServiceAccountEmail = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", cfg.ServiceAccountName, cfg.ProjectID)
+
 	fmt.Println()
 	fmt.Println("==============================================")
 	fmt.Println("  Project Configuration Summary")
 	fmt.Println("==============================================")
 	fmt.Printf("  Project Name:         %s\n", cfg.ProjectName)
 	fmt.Printf("  Project ID:           %s\n", cfg.ProjectID)
+	if cfg.CIProvider == "gitlab" {
+		fmt.Printf("  GitLab:               %s (%s)\n", cfg.GitLabProject, cfg.GitLabURL)
+	} else {
+		fmt.Printf("  GitHub:               %s/%s\n", cfg.GitHubOrg, cfg.GitHubRepo)
+	}
 	fmt.Printf("  Service Account:      %s\n", cfg.ServiceAccountName)
 	fmt.Printf("  Artifact Registry:    %s (%s)\n", cfg.ArtifactRegistryName, cfg.ArtifactRegistryLocation)
 	fmt.Println("==============================================")
@@ -99,7 +133,7 @@ func runProjectCreate(cmd *cobra.Command, args []string) error {
 
 	steps := []struct {
 		name string
-		fn   func(ProjectConfig) error
+		fn   func(*ProjectConfig) error
 	}{
 		{"Creating GCP Project", createGCPProject},
 		{"Enabling APIs", enableProjectAPIs},
@@ -111,7 +145,7 @@ func runProjectCreate(cmd *cobra.Command, args []string) error {
 	for i, step := range steps {
 		fmt.Printf("Step %d/%d: %s...\n", i+1, len(steps), step.name)
 		fmt.Println("----------------------------------------------")
-		if err := step.fn(cfg); err != nil {
+		if err := step.fn(&cfg); err != nil {
 			return fmt.Errorf("%s failed: %w", step.name, err)
 		}
 		fmt.Println()
@@ -121,11 +155,20 @@ func runProjectCreate(cmd *cobra.Command, args []string) error {
 	fmt.Println("  Project Creation Complete!")
 	fmt.Println("==============================================")
 	fmt.Println()
-	fmt.Printf("Project ID: %s\n", cfg.ProjectID)
+	fmt.Printf("Project ID:                 %s\n", cfg.ProjectID)
+	fmt.Printf("Project Number:             %s\n", cfg.ProjectNumber)
+	fmt.Printf("Service Account:            %s\n", cfg.ServiceAccountEmail)
+	fmt.Printf("Workload Identity Provider: %s\n", cfg.WorkloadIdentityProvider)
+	fmt.Printf("Artifact Registry:          %s\n", cfg.ArtifactRegistryURL)
+	fmt.Println()
 	fmt.Println("Next steps:")
 	fmt.Println("  1. Save the project ID for later use")
 	fmt.Println("  2. Run: gcsetup service setup")
-	fmt.Println("     (with your GitHub org/repo and cloud run service details)")
+	if cfg.CIProvider == "gitlab" {
+		fmt.Println("     (with your GitLab project and cloud run service details)")
+	} else {
+		fmt.Println("     (with your GitHub org/repo and cloud run service details)")
+	}
 
 	return nil
 }
@@ -134,6 +177,9 @@ func interactiveProjectConfig(cfg *ProjectConfig) error {
 	cfg.ProjectName = promptProject("Project Name", "my-project")
 	cfg.ProjectID = promptProject("Project ID (must be globally unique)", "my-project-"+randomSuffix())
 
+	fmt.Println()
+	promptProjectRepository(cfg)
+
 	fmt.Println()
 	cfg.ServiceAccountName = promptProject("Service Account Name", "github-actions")
 	cfg.ArtifactRegistryName = promptProject("Artifact Registry Name", "docker")
@@ -142,9 +188,27 @@ func interactiveProjectConfig(cfg *ProjectConfig) error {
 	return nil
 }
 
-func createGCPProject(cfg ProjectConfig) error {
+// promptProjectRepository asks for the repository or GitLab project whose CI
+// may impersonate the service account.
+func promptProjectRepository(cfg *ProjectConfig) {
+	if cfg.CIProvider == "gitlab" {
+		cfg.GitLabURL = strings.TrimSuffix(promptProject("GitLab URL", gitlabURL()), "/")
+		project := viper.GetString("GCP_GITLAB_PROJECT")
+		if host, path := originRemote(); project == "" && host != "" && "https://"+host == cfg.GitLabURL {
+			project = path
+		}
+		cfg.GitLabProject = promptProject("GitLab Project", project)
+		return
+	}
+	org, repo := detectGitHubRepo()
+	cfg.GitHubOrg = promptProject("GitHub Organization", org)
+	cfg.GitHubRepo = promptProject("GitHub Repository", repo)
+}
+
+func createGCPProject(cfg *ProjectConfig) error {
 	if projectDryRun {
 		fmt.Printf("  [dry-run] gcloud projects create %s --name=\"%s\"\n", cfg.ProjectID, cfg.ProjectName)
+		cfg.ProjectNumber = "PROJECT_NUMBER"
 		return nil
 	}
 
@@ -154,16 +218,24 @@ func createGCPProject(cfg ProjectConfig) error {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
 	fmt.Printf("  ✓ Project '%s' created\n", cfg.ProjectID)
+
+	output, err := exec.Command("gcloud", "projects", "describe", cfg.ProjectID,
+		"--format=value(projectNumber)").Output()
+	if err != nil {
+		return fmt.Errorf("failed to get project number: %w", err)
+	}
+	cfg.ProjectNumber = strings.TrimSpace(string(output))
 	return nil
 }
 
-func enableProjectAPIs(cfg ProjectConfig) error {
+func enableProjectAPIs(cfg *ProjectConfig) error {
 	apis := []string{
 		"cloudresourcemanager.googleapis.com",
 		"serviceusage.googleapis.com",
 		"iam.googleapis.com",
 		"artifactregistry.googleapis.com",
 		"iamcredentials.googleapis.com",
+		"sts.googleapis.com",
 		"cloudkms.googleapis.com",
 	}
 
@@ -187,9 +259,7 @@ func enableProjectAPIs(cfg ProjectConfig) error {
 	return nil
 }
 
-func createProjectServiceAccount(cfg ProjectConfig) error {
-	cfg.ServiceAccountEmail = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", cfg.ServiceAccountName, cfg.ProjectID)
-
+func createProjectServiceAccount(cfg *ProjectConfig) error {
 	if projectDryRun {
 		fmt.Printf("  [dry-run] gcloud iam service-accounts create %s "+
 			"--project=%s\n", cfg.ServiceAccountName, cfg.ProjectID)
@@ -206,41 +276,24 @@ func createProjectServiceAccount(cfg ProjectConfig) error {
 	return nil
 }
 
-func setupProjectWorkloadIdentity(cfg ProjectConfig) error {
-	if projectDryRun {
-		fmt.Println("  [dry-run] Setting up Workload Identity Federation")
-		fmt.Printf("  [dry-run] gcloud iam workload-identity-pools create "+
-			"github-pool --project=%s --location=global\n", cfg.ProjectID)
-		fmt.Println("  [dry-run] gcloud iam workload-identity-pools providers create-oidc github-provider ...")
-		return nil
+func setupProjectWorkloadIdentity(cfg *ProjectConfig) error {
+	policy := loadWIFPolicy(githubIssuer(githubHost()), cfg.GitHubOrg, cfg.GitHubRepo)
+	if cfg.CIProvider == "gitlab" {
+		policy = loadWIFPolicy(gitlabIssuer(cfg.GitLabURL), gitlabNamespace(cfg.GitLabProject), cfg.GitLabProject)
 	}
-
-	fmt.Println("  Setting up Workload Identity Federation...")
-
-	fmt.Println("    Creating workload identity pool 'github-pool'")
-	cmd := exec.Command("gcloud", "iam", "workload-identity-pools", "create", "github-pool",
-		"--project="+cfg.ProjectID, "--location=global", "--display-name=GitHub")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create workload identity pool: %w", err)
-	}
-
-	fmt.Println("    Creating OIDC provider 'github-provider'")
-	cmd = exec.Command("gcloud", "iam", "workload-identity-pools", "providers", "create-oidc", "github-provider",
-		"--project="+cfg.ProjectID,
-		"--location=global",
-		"--workload-identity-pool=github-pool",
-		"--display-name=GitHub",
-		"--attribute-mapping=google.subject=assertion.sub,assertion.aud=assertion.aud",
-		"--issuer-uri=https://token.actions.githubusercontent.com")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create OIDC provider: %w", err)
+	if err := ensureWorkloadIdentity(projectDryRun, wifTarget{
+		ProjectID:           cfg.ProjectID,
+		ProjectNumber:       cfg.ProjectNumber,
+		ServiceAccountEmail: cfg.ServiceAccountEmail,
+		Policy:              policy,
+	}); err != nil {
+		return err
 	}
-
-	fmt.Println("  ✓ Workload Identity Federation configured")
+	cfg.WorkloadIdentityProvider = policy.Issuer.providerName(cfg.ProjectNumber)
 	return nil
 }
 
-func createProjectArtifactRegistry(cfg ProjectConfig) error {
+func createProjectArtifactRegistry(cfg *ProjectConfig) error {
 	cfg.ArtifactRegistryURL = fmt.Sprintf("%s-docker.pkg.dev/%s/%s", cfg.ArtifactRegistryLocation,
 		cfg.ProjectID, cfg.ArtifactRegistryName)
 
@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var repoCmd = &cobra.Command{
+	Use:   "repo",
+	Short: "Manage the GitHub repositories allowed to deploy",
+	Long: `Commands for granting additional GitHub repositories access to the deployer
+service account through the existing Workload Identity pool.`,
+}
+
+var repoAddCmd = &cobra.Command{
+	Use:   "add <org/repo>",
+	Short: "Allow a repository to deploy and configure its secrets and variables",
+	Long: `Allow an additional repository to deploy:
+  1. Add the repository to the provider's attribute condition
+  2. Grant it roles/iam.workloadIdentityUser on the service account
+  3. Configure its GitHub secrets, variables and environments`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRepoAdd,
+}
+
+var repoRemoveCmd = &cobra.Command{
+	Use:   "remove <org/repo>",
+	Short: "Revoke the access of a repository",
+	Long: `Revoke the access of a repository added with 'gcsetup repo add'. The binding
+and the repository's entry in the attribute condition are removed; its GitHub
+secrets and variables are left untouched.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRepoRemove,
+}
+
+var repoListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the repositories allowed to deploy",
+	RunE:  runRepoList,
+}
+
+func init() {
+	rootCmd.AddCommand(repoCmd)
+	repoCmd.AddCommand(repoAddCmd, repoRemoveCmd, repoListCmd)
+	repoCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print commands without executing")
+	repoCmd.PersistentFlags().BoolVarP(&nonInteractive, "yes", "y", false,
+		"Non-interactive mode (accept all defaults)")
+	repoAddCmd.Flags().BoolVar(&forceSecrets, "force", false, "Overwrite existing CI secrets")
+}
+
+// repoConfig loads the service configuration the repo commands work on.
+func repoConfig() (Config, error) {
+	if viper.GetString("GCP_PROJECT_ID") == "" {
+		return Config{}, fmt.Errorf("GCP_PROJECT_ID is required")
+	}
+	if viper.GetString("GCP_SERVICE_ACCOUNT_NAME") == "" {
+		viper.Set("GCP_SERVICE_ACCOUNT_NAME", "github-actions")
+	}
+	if viper.GetString("GCP_PROJECT_NUMBER") == "" {
+		output, err := exec.Command("gcloud", "projects", "describe", viper.GetString("GCP_PROJECT_ID"),
+			"--format=value(projectNumber)").Output()
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to get project number: %w", err)
+		}
+		viper.Set("GCP_PROJECT_NUMBER", strings.TrimSpace(string(output)))
+	}
+
+	if viper.GetString("GCP_GITHUB_ORGANIZATION") == "" {
+		org, repo := detectGitHubRepo()
+		viper.Set("GCP_GITHUB_ORGANIZATION", org)
+		viper.Set("GCP_GITHUB_REPOSITORY", repo)
+	}
+
+	cfg := loadConfig()
+	if cfg.CIProvider != "github" {
+		return Config{}, fmt.Errorf("repo commands support GitHub repositories only")
+	}
+	if cfg.GitHubOrg == "" {
+		return Config{}, fmt.Errorf("GCP_GITHUB_ORGANIZATION is required")
+	}
+	return cfg, nil
+}
+
+// setConfigValue sets key in the config file in use, replacing an existing
+// (possibly commented out) assignment or appending one.
+func setConfigValue(key, value string) error {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		path = ".env.gcloud"
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	line := key + "=" + value
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	replaced := false
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "#"))
+		if strings.HasPrefix(trimmed, key+"=") {
+			lines[i] = line
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, line)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("  ✓ %s saved to %s\n", key, path)
+	return nil
+}
+
+// parseRepoArg splits an org/repo argument.
+func parseRepoArg(arg string) (org, repo string, err error) {
+	org, repo, ok := strings.Cut(arg, "/")
+	if !ok || org == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid repository %q, expected org/repo", arg)
+	}
+	return org, repo, nil
+}
+
+func runRepoAdd(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+	if err := checkGH(); err != nil {
+		return err
+	}
+
+	org, repo, err := parseRepoArg(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := repoConfig()
+	if err != nil {
+		return err
+	}
+
+	fullName := org + "/" + repo
+	policyChanged := !cfg.WIF.allRepositories() && !slices.Contains(cfg.WIF.repositories(), fullName)
+	if cfg.WIF.allRepositories() && org != cfg.WIF.Owner {
+		return fmt.Errorf("the policy allows all repositories of %s; add %s to GCP_WIF_REPOSITORIES explicitly",
+			cfg.WIF.Owner, fullName)
+	}
+	if policyChanged {
+		cfg.WIF.Repositories = append(cfg.WIF.Repositories, fullName)
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Adding Repository '%s'\n", fullName)
+	fmt.Println("==============================================")
+	fmt.Printf("  Service Account:      %s\n", cfg.ServiceAccountEmail)
+	fmt.Printf("  WIF Condition:        %s\n", cfg.WIF.attributeCondition())
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	if !nonInteractive {
+		if !promptConfirm("Proceed?") {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	repoCfg := cfg
+	repoCfg.GitHubOrg, repoCfg.GitHubRepo = org, repo
+
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"Updating Workload Identity provider", func() error {
+			if !policyChanged {
+				fmt.Println("  ✓ Repository already allowed by the attribute condition")
+				return nil
+			}
+			if err := updateProviderPolicy(dryRun, cfg.ProjectID, cfg.WIF); err != nil {
+				return err
+			}
+			fmt.Println("  ✓ Attribute condition updated")
+			return nil
+		}},
+		{"Granting repository access", func() error {
+			return grantRepoAccess(cfg, fullName)
+		}},
+		{"Configuring GitHub Repository", func() error {
+			return configureGitHub(repoCfg)
+		}},
+	}
+
+	for i, step := range steps {
+		fmt.Printf("Step %d/%d: %s...\n", i+1, len(steps), step.name)
+		fmt.Println("----------------------------------------------")
+		if err := step.fn(); err != nil {
+			return fmt.Errorf("%s failed: %w", step.name, err)
+		}
+		fmt.Println()
+	}
+
+	if policyChanged && !dryRun {
+		if err := setConfigValue("GCP_WIF_REPOSITORIES", strings.Join(cfg.WIF.Repositories, ",")); err != nil {
+			fmt.Printf("Warning: Could not save config: %v\n", err)
+		}
+	}
+
+	fmt.Println("==============================================")
+	fmt.Println("  Repository Added!")
+	fmt.Println("==============================================")
+	fmt.Println()
+	fmt.Printf("%s can now deploy as %s.\n", fullName, cfg.ServiceAccountEmail)
+	fmt.Println("Copy the deploy workflow into the repository with: gcsetup init")
+
+	return nil
+}
+
+func grantRepoAccess(cfg Config, fullName string) error {
+	if cfg.WIF.allRepositories() {
+		fmt.Printf("  ✓ All repositories of %s are bound\n", cfg.WIF.Owner)
+		return nil
+	}
+	member := cfg.WIF.repoMember(cfg.ProjectNumber, fullName)
+	if err := runGcloud(dryRun, "iam", "service-accounts", "add-iam-policy-binding", cfg.ServiceAccountEmail,
+		"--project="+cfg.ProjectID,
+		"--role=roles/iam.workloadIdentityUser",
+		"--member="+member,
+	); err != nil {
+		return fmt.Errorf("failed to grant %s: %w", fullName, err)
+	}
+	fmt.Printf("  ✓ roles/iam.workloadIdentityUser granted to %s\n", fullName)
+	return nil
+}
+
+func runRepoRemove(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+
+	org, repo, err := parseRepoArg(args[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := repoConfig()
+	if err != nil {
+		return err
+	}
+
+	fullName := org + "/" + repo
+	if org == cfg.GitHubOrg && repo == cfg.GitHubRepo {
+		return fmt.Errorf("%s is the primary repository (GCP_GITHUB_REPOSITORY) and cannot be removed", fullName)
+	}
+
+	var remaining []string
+	for _, r := range cfg.WIF.Repositories {
+		if r != fullName && !(org == cfg.WIF.Owner && r == repo) {
+			remaining = append(remaining, r)
+		}
+	}
+	policyChanged := len(remaining) != len(cfg.WIF.Repositories)
+	// An empty list admits every repository of the owner, so fall back to
+	// the primary repository, which is also the default of the list.
+	if policyChanged && len(remaining) == 0 {
+		if cfg.GitHubRepo == "" {
+			return fmt.Errorf("%s is the only allowed repository; removing it would allow all repositories of %s",
+				fullName, cfg.WIF.Owner)
+		}
+		remaining = []string{cfg.GitHubRepo}
+	}
+	cfg.WIF.Repositories = remaining
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Removing Repository '%s'\n", fullName)
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	if !nonInteractive {
+		if !promptConfirm("Proceed?") {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+		fmt.Println()
+	}
+
+	member := cfg.WIF.repoMember(cfg.ProjectNumber, fullName)
+	if err := runGcloud(dryRun, "iam", "service-accounts", "remove-iam-policy-binding", cfg.ServiceAccountEmail,
+		"--project="+cfg.ProjectID,
+		"--role=roles/iam.workloadIdentityUser",
+		"--member="+member,
+	); err != nil {
+		fmt.Printf("  ⚠ Could not remove binding (may not exist): %v\n", err)
+	} else {
+		fmt.Printf("  ✓ roles/iam.workloadIdentityUser revoked from %s\n", fullName)
+	}
+
+	if policyChanged {
+		if err := updateProviderPolicy(dryRun, cfg.ProjectID, cfg.WIF); err != nil {
+			return err
+		}
+		fmt.Println("  ✓ Attribute condition updated")
+		if !dryRun {
+			if err := setConfigValue("GCP_WIF_REPOSITORIES", strings.Join(cfg.WIF.Repositories, ",")); err != nil {
+				fmt.Printf("Warning: Could not save config: %v\n", err)
+			}
+		}
+	} else if cfg.WIF.allRepositories() {
+		fmt.Printf("  ⚠ The policy still allows all repositories of %s; restrict GCP_WIF_REPOSITORIES to revoke %s\n",
+			cfg.WIF.Owner, fullName)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s can no longer deploy. Its GitHub secrets and variables were left in place.\n", fullName)
+	return nil
+}
+
+type iamPolicy struct {
+	Bindings []struct {
+		Role    string   `json:"role"`
+		Members []string `json:"members"`
+	} `json:"bindings"`
+}
+
+func runRepoList(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+	cfg, err := repoConfig()
+	if err != nil {
+		return err
+	}
+
+	var policy iamPolicy
+	if err := gcloudJSON(&policy, "iam", "service-accounts", "get-iam-policy", cfg.ServiceAccountEmail,
+		"--project="+cfg.ProjectID); err != nil {
+		return fmt.Errorf("failed to read IAM policy of %s: %w", cfg.ServiceAccountEmail, err)
+	}
+
+	allowed := cfg.WIF.repositories()
+	fmt.Printf("Repositories allowed to deploy as %s:\n", cfg.ServiceAccountEmail)
+	found := false
+	for _, b := range policy.Bindings {
+		if b.Role != "roles/iam.workloadIdentityUser" {
+			continue
+		}
+		for _, m := range b.Members {
+			_, attr, ok := strings.Cut(m, "/workloadIdentityPools/"+cfg.WIF.Issuer.Pool+"/")
+			if !ok {
+				continue
+			}
+			found = true
+			switch {
+			case strings.HasPrefix(attr, "attribute.repository/"):
+				name := strings.TrimPrefix(attr, "attribute.repository/")
+				note := ""
+				if !cfg.WIF.allRepositories() && !slices.Contains(allowed, name) {
+					note = "  (⚠ not in attribute condition)"
+				}
+				fmt.Printf("  %s%s\n", name, note)
+			case strings.HasPrefix(attr, "attribute.repository_owner/"):
+				fmt.Printf("  %s/*\n", strings.TrimPrefix(attr, "attribute.repository_owner/"))
+			default:
+				fmt.Printf("  %s\n", attr)
+			}
+		}
+	}
+	if !found {
+		fmt.Println("  (none)")
+	}
+	return nil
+}
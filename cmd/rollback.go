@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Route all traffic of a Cloud Run service back to a previous revision",
+	Long: `Lists the recent revisions of the environment's Cloud Run service with the
+git commit and image tags they were deployed from, and routes 100% of the
+traffic to the chosen one. Without --to, the default choice is the newest
+ready revision older than the one currently serving the most traffic that
+has served requests, so candidates that never received traffic are skipped.
+
+The traffic stays pinned to that revision: later deploys that do not migrate
+traffic themselves receive none until
+  gcloud run services update-traffic <service> --to-latest
+
+Example:
+  gcsetup rollback                        # choose from the last 10 revisions
+  gcsetup rollback --to shop-00041-abc -y`,
+	RunE: runRollback,
+}
+
+var (
+	rollbackEnvironment    string
+	rollbackService        string
+	rollbackTo             string
+	rollbackLimit          int
+	rollbackDryRun         bool
+	rollbackNonInteractive bool
+)
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().StringVarP(&rollbackEnvironment, "environment", "e", "production",
+		"Environment to roll back: "+strings.Join(deployEnvironments, ", "))
+	rollbackCmd.Flags().StringVar(&rollbackService, "service", "", "Cloud Run service (default: the environment's)")
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "Revision to roll back to (default: the previous one)")
+	rollbackCmd.Flags().IntVarP(&rollbackLimit, "limit", "n", 10, "Number of revisions to choose from")
+	rollbackCmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false, "Print commands without executing")
+	rollbackCmd.Flags().BoolVarP(&rollbackNonInteractive, "yes", "y", false,
+		"Roll back to --to or the previous revision without asking")
+}
+
+// dockerImageTags is the tags field of gcloud artifacts docker images list,
+// a comma-separated string in older gcloud versions and a list in newer ones.
+type dockerImageTags []string
+
+func (t *dockerImageTags) UnmarshalJSON(b []byte) error {
+	var list []string
+	if err := json.Unmarshal(b, &list); err == nil {
+		*t = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*t = splitList(s)
+	return nil
+}
+
+// imageTags returns the Artifact Registry tags of the revisions' images by
+// image digest.
+func imageTags(revisions []cloudRunRevision) (map[string][]string, error) {
+	tags := map[string][]string{}
+	seen := map[string]bool{}
+	for _, r := range revisions {
+		image, _, ok := strings.Cut(r.Status.ImageDigest, "@")
+		if !ok || seen[image] {
+			continue
+		}
+		seen[image] = true
+
+		var versions []struct {
+			Package string          `json:"package"`
+			Version string          `json:"version"`
+			Tags    dockerImageTags `json:"tags"`
+		}
+		if err := gcloudJSON(&versions, "artifacts", "docker", "images", "list", image, "--include-tags"); err != nil {
+			return tags, fmt.Errorf("failed to list the tags of %s: %w", image, err)
+		}
+		for _, v := range versions {
+			tags[image+"@"+v.Version] = v.Tags
+		}
+	}
+	return tags, nil
+}
+
+// previousRevision returns the revision that served before the one serving
+// the most traffic: the next older revision that is ready and has served
+// requests, which skips candidates that failed before receiving traffic.
+// When none has served, e.g. as their logs have expired, it falls back to
+// the next older ready revision. It returns -1 if there is none.
+func previousRevision(revisions []cloudRunRevision, serving string, served func(string) bool) int {
+	start := -1
+	for i, r := range revisions {
+		if r.Metadata.Name == serving {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return -1
+	}
+	fallback := -1
+	for i := start; i < len(revisions); i++ {
+		if !revisions[i].ready() {
+			continue
+		}
+		if served(revisions[i].Metadata.Name) {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// revisionServed reports whether the request logs of the last 30 days show
+// the revision serving a request.
+func revisionServed(t deployTarget, revision string) bool {
+	filter := fmt.Sprintf(`resource.type="cloud_run_revision" AND resource.labels.service_name=%q `+
+		`AND resource.labels.revision_name=%q AND httpRequest.status>0`, t.Service, revision)
+	output, err := exec.Command("gcloud", "logging", "read", filter, "--project="+t.ProjectID,
+		"--freshness=30d", "--limit=1", "--format=value(httpRequest.status)").Output()
+	return err == nil && strings.TrimSpace(string(output)) != ""
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+	t, err := resolveDeployTarget(rollbackEnvironment, rollbackService)
+	if err != nil {
+		return err
+	}
+
+	svc, err := describeService(t)
+	if err != nil {
+		return err
+	}
+	revisions, err := listRevisions(t, rollbackLimit)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return fmt.Errorf("%s has no revisions", t.Service)
+	}
+	tags, err := imageTags(revisions)
+	if err != nil {
+		fmt.Printf("⚠ %v\n", err)
+	}
+
+	fmt.Printf("%s (%s) in %s, %s\n", t.Service, t.Environment, t.ProjectID, t.Region)
+	fmt.Println()
+	traffic := svc.traffic()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "#\tREVISION\tDEPLOYED\tTRAFFIC\tCOMMIT\tTAGS")
+	for i, r := range revisions {
+		// The SHA tag of the build duplicates the commit column.
+		var shown []string
+		for _, tag := range tags[r.Status.ImageDigest] {
+			if tag != r.Metadata.Labels["commit-sha"] {
+				shown = append(shown, tag)
+			}
+		}
+		if len(shown) == 0 {
+			shown = []string{"-"}
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, r.Metadata.Name, r.deployed(),
+			trafficPercent(traffic, r.Metadata.Name), r.commit(), strings.Join(shown, ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	target := rollbackTo
+	if target == "" {
+		idx := previousRevision(revisions, svc.servingRevision(), func(revision string) bool {
+			return revisionServed(t, revision)
+		})
+		if !rollbackNonInteractive {
+			if idx >= 0 {
+				fmt.Printf("Select revision [%d]: ", idx+1)
+			} else {
+				fmt.Print("Select revision: ")
+			}
+			input, _ := reader.ReadString('\n')
+			if input = strings.TrimSpace(input); input != "" {
+				idx = -1
+				_, _ = fmt.Sscanf(input, "%d", &idx)
+				idx--
+			}
+		}
+		if idx < 0 || idx >= len(revisions) {
+			return fmt.Errorf("no revision selected, use --to to name one")
+		}
+		target = revisions[idx].Metadata.Name
+	}
+	if traffic[target] == 100 {
+		fmt.Printf("✓ %s already serves all traffic\n", target)
+		return nil
+	}
+
+	if !rollbackNonInteractive && !rollbackDryRun &&
+		!promptConfirm(fmt.Sprintf("Route 100%% of %s traffic to %s?", t.Service, target)) {
+		fmt.Println("Aborted")
+		return nil
+	}
+	if err := runGcloud(rollbackDryRun, append([]string{"run", "services", "update-traffic", t.Service,
+		"--to-revisions=" + target + "=100"}, t.gcloudFlags()...)...); err != nil {
+		return fmt.Errorf("failed to roll back %s: %w", t.Service, err)
+	}
+	if rollbackDryRun {
+		return nil
+	}
+	fmt.Printf("✓ %s serves all traffic of %s\n", target, t.Service)
+	fmt.Printf("⚠ Traffic is pinned; run gcloud run services update-traffic %s --to-latest %s "+
+		"once a fixed revision is deployed\n", t.Service, strings.Join(t.gcloudFlags(), " "))
+	return nil
+}
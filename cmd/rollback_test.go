@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPreviousRevision(t *testing.T) {
+	revision := func(name string, ready bool) cloudRunRevision {
+		var r cloudRunRevision
+		r.Metadata.Name = name
+		status := "False"
+		if ready {
+			status = "True"
+		}
+		r.Status.Conditions = append(r.Status.Conditions, struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		}{"Ready", status})
+		return r
+	}
+	revisions := []cloudRunRevision{
+		revision("shop-5", true),
+		revision("shop-4", true),
+		revision("shop-3", false),
+		revision("shop-2", true),
+		revision("shop-1", true),
+	}
+
+	tests := []struct {
+		name    string
+		serving string
+		served  []string
+		want    int
+	}{
+		{"previous revision served", "shop-5", []string{"shop-4", "shop-2"}, 1},
+		{"skips candidates without traffic", "shop-5", []string{"shop-2"}, 3},
+		{"skips revisions that are not ready", "shop-4", []string{"shop-3", "shop-1"}, 4},
+		{"falls back to the next ready revision", "shop-4", nil, 3},
+		{"oldest revision serving", "shop-1", nil, -1},
+		{"serving revision not listed", "shop-0", nil, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := func(name string) bool { return slices.Contains(tt.served, name) }
+			if got := previousRevision(revisions, tt.serving, served); got != tt.want {
+				t.Errorf("previousRevision(%s) = %d, want %d", tt.serving, got, tt.want)
+			}
+		})
+	}
+}
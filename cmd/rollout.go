@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Gradually move the traffic of a Cloud Run service to a new revision",
+	Long: `Deploys --image as a new revision without traffic, or takes an existing
+--revision, and moves the traffic of the environment's service to it in steps.
+The new revision is tagged "candidate", so it is reachable at
+https://candidate---<service host> throughout.
+
+Between steps the command waits for --interval, or asks for confirmation
+when no interval is given, and then checks the new revision:
+  - GCP_WORKFLOW_SMOKE_PATH, if set, must answer with GCP_WORKFLOW_SMOKE_STATUS
+    within 5 attempts, 10s apart
+  - its 5xx rate since the last step must not exceed the previous revision's
+    by more than --error-threshold percentage points, once it served
+    --min-requests requests
+
+When a check fails, all traffic is moved back to the previous revision.
+
+Example:
+  gcsetup rollout --image europe-west1-docker.pkg.dev/acme/docker/api@sha256:... --steps 10,50,100 --interval 10m`,
+	RunE: runRollout,
+}
+
+// rolloutMinRequests is the default number of requests a new revision must
+// serve before its error rate is compared.
+const rolloutMinRequests = 20
+
+// smokeAttempts and smokeRetryDelay match the smoke test step of the workflow.
+const (
+	smokeAttempts   = 5
+	smokeRetryDelay = 10 * time.Second
+)
+
+var smokeClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	rolloutEnvironment    string
+	rolloutService        string
+	rolloutImage          string
+	rolloutRevision       string
+	rolloutSteps          []int
+	rolloutInterval       time.Duration
+	rolloutErrorThreshold float64
+	rolloutMinReqs        int
+	rolloutDryRun         bool
+	rolloutNonInteractive bool
+)
+
+func init() {
+	rootCmd.AddCommand(rolloutCmd)
+	rolloutCmd.Flags().StringVarP(&rolloutEnvironment, "environment", "e", "production",
+		"Environment to roll out: "+strings.Join(deployEnvironments, ", "))
+	rolloutCmd.Flags().StringVar(&rolloutService, "service", "", "Cloud Run service (default: the environment's)")
+	rolloutCmd.Flags().StringVar(&rolloutImage, "image", "", "Image to deploy as the new revision")
+	rolloutCmd.Flags().StringVar(&rolloutRevision, "revision", "", "Existing revision to roll out instead of --image")
+	rolloutCmd.Flags().IntSliceVar(&rolloutSteps, "steps", []int{10, 50, 100},
+		"Percentages sent to the new revision per step, ending with 100")
+	rolloutCmd.Flags().DurationVar(&rolloutInterval, "interval", 0, "Pause between steps")
+	rolloutCmd.Flags().Float64Var(&rolloutErrorThreshold, "error-threshold", 1,
+		"Percentage points the new revision's 5xx rate may exceed the previous one's")
+	rolloutCmd.Flags().IntVar(&rolloutMinReqs, "min-requests", rolloutMinRequests,
+		"Requests the new revision must serve before error rates are compared")
+	rolloutCmd.Flags().BoolVar(&rolloutDryRun, "dry-run", false, "Print commands without executing")
+	rolloutCmd.Flags().BoolVarP(&rolloutNonInteractive, "yes", "y", false, "Continue between steps without asking")
+	rolloutCmd.MarkFlagsMutuallyExclusive("image", "revision")
+	rolloutCmd.MarkFlagsOneRequired("image", "revision")
+}
+
+// validateTrafficSteps checks that steps are increasing percentages.
+func validateTrafficSteps(steps []int) error {
+	prev := 0
+	for _, step := range steps {
+		if step <= prev || step > 100 {
+			return fmt.Errorf("steps must be increasing percentages between 1 and 100")
+		}
+		prev = step
+	}
+	return nil
+}
+
+// parseTrafficSteps parses comma-separated traffic percentages, e.g. "10,50,100".
+func parseTrafficSteps(s string) ([]int, error) {
+	var steps []int
+	for _, part := range splitList(s) {
+		step, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid traffic step %q", part)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("no traffic steps")
+	}
+	return steps, validateTrafficSteps(steps)
+}
+
+// servingRevision returns the revision receiving the most traffic.
+func (s cloudRunService) servingRevision() string {
+	name, most := "", -1
+	for revision, percent := range s.traffic() {
+		if percent > most || percent == most && revision < name {
+			name, most = revision, percent
+		}
+	}
+	return name
+}
+
+func runRollout(cmd *cobra.Command, args []string) error {
+	if err := checkGcloud(); err != nil {
+		return err
+	}
+	if err := validateTrafficSteps(rolloutSteps); err != nil {
+		return fmt.Errorf("--%w", err)
+	}
+	if rolloutSteps[len(rolloutSteps)-1] != 100 {
+		return fmt.Errorf("--steps must end with 100, or the previous revision keeps part of the traffic")
+	}
+	t, err := resolveDeployTarget(rolloutEnvironment, rolloutService)
+	if err != nil {
+		return err
+	}
+
+	svc, err := describeService(t)
+	if err != nil {
+		return err
+	}
+	previous := svc.servingRevision()
+	if previous == "" {
+		return fmt.Errorf("%s serves no revision yet, deploy it first", t.Service)
+	}
+
+	fmt.Println()
+	fmt.Println("==============================================")
+	fmt.Printf("  Rolling out %s (%s)\n", t.Service, t.Environment)
+	fmt.Println("==============================================")
+	fmt.Printf("  Previous:  %s\n", previous)
+	if rolloutImage != "" {
+		fmt.Printf("  Image:     %s\n", rolloutImage)
+	} else {
+		fmt.Printf("  Revision:  %s\n", rolloutRevision)
+	}
+	fmt.Printf("  Steps:     %v\n", rolloutSteps)
+	fmt.Println("==============================================")
+	fmt.Println()
+
+	candidate := rolloutRevision
+	if rolloutImage != "" {
+		fmt.Println("Deploying the new revision without traffic...")
+		if err := runGcloud(rolloutDryRun, append([]string{"run", "deploy", t.Service, "--image=" + rolloutImage,
+			"--no-traffic", "--tag=candidate", "--quiet"}, t.gcloudFlags()...)...); err != nil {
+			return fmt.Errorf("failed to deploy %s: %w", rolloutImage, err)
+		}
+		candidate = "NEW_REVISION"
+		if !rolloutDryRun {
+			if svc, err = describeService(t); err != nil {
+				return err
+			}
+			candidate = svc.Status.LatestCreatedRevisionName
+		}
+		fmt.Printf("  ✓ Deployed %s\n", candidate)
+	} else if err := runGcloud(rolloutDryRun, append([]string{"run", "services", "update-traffic", t.Service,
+		"--update-tags=candidate=" + candidate}, t.gcloudFlags()...)...); err != nil {
+		return fmt.Errorf("failed to tag %s: %w", candidate, err)
+	}
+	if candidate == previous {
+		return fmt.Errorf("%s already serves the most traffic", candidate)
+	}
+	fmt.Println()
+
+	since := time.Now()
+	for i, step := range rolloutSteps {
+		if i > 0 {
+			if rolloutInterval > 0 {
+				fmt.Printf("  Waiting %s before the next step...\n", rolloutInterval)
+				if !rolloutDryRun {
+					time.Sleep(rolloutInterval)
+				}
+			} else if !rolloutNonInteractive && !rolloutDryRun &&
+				!promptConfirm(fmt.Sprintf("Continue to %d%%?", step)) {
+				fmt.Printf("Rollout paused; %s keeps %d%% of the traffic.\n", candidate, rolloutSteps[i-1])
+				return nil
+			}
+
+			if err := checkCandidate(t, svc, candidate, previous, since); err != nil {
+				fmt.Printf("  ✗ %v\n", err)
+				fmt.Printf("  Rolling back to %s...\n", previous)
+				if rbErr := routeTraffic(t, previous, 100, ""); rbErr != nil {
+					return fmt.Errorf("rollback to %s failed: %w", previous, rbErr)
+				}
+				return fmt.Errorf("rolled back to %s: %w", previous, err)
+			}
+		}
+
+		fmt.Printf("Step %d/%d: %d%% to %s\n", i+1, len(rolloutSteps), step, candidate)
+		fmt.Println("----------------------------------------------")
+		if err := routeTraffic(t, candidate, step, previous); err != nil {
+			return err
+		}
+		since = time.Now()
+		fmt.Printf("  ✓ %s serves %d%% of %s\n", candidate, step, t.Service)
+		fmt.Println()
+	}
+
+	fmt.Println("==============================================")
+	fmt.Println("  Rollout Complete!")
+	fmt.Println("==============================================")
+	return nil
+}
+
+// routeTraffic sends percent of the traffic to revision and the rest to rest.
+func routeTraffic(t deployTarget, revision string, percent int, rest string) error {
+	to := fmt.Sprintf("%s=%d", revision, percent)
+	if percent < 100 {
+		to += fmt.Sprintf(",%s=%d", rest, 100-percent)
+	}
+	if err := runGcloud(rolloutDryRun, append([]string{"run", "services", "update-traffic", t.Service,
+		"--to-revisions=" + to}, t.gcloudFlags()...)...); err != nil {
+		return fmt.Errorf("failed to update the traffic of %s: %w", t.Service, err)
+	}
+	return nil
+}
+
+// checkCandidate runs the smoke test against the candidate's tagged URL and
+// compares its error rate since the last step with the previous revision's.
+func checkCandidate(t deployTarget, svc cloudRunService, candidate, previous string, since time.Time) error {
+	if rolloutDryRun {
+		fmt.Printf("  [dry-run] Would compare the error rates of %s and %s\n", candidate, previous)
+		return nil
+	}
+
+	if path := viper.GetString("GCP_WORKFLOW_SMOKE_PATH"); path != "" {
+		want := viper.GetString("GCP_WORKFLOW_SMOKE_STATUS")
+		if want == "" {
+			want = "200"
+		}
+		url := "https://candidate---" + strings.TrimPrefix(svc.Status.URL, "https://") + path
+		if err := smokeTest(url, want); err != nil {
+			return err
+		}
+		fmt.Printf("  ✓ GET %s returned %s\n", path, want)
+	}
+
+	newRequests, newRate, err := revisionErrorRate(t, candidate, since)
+	if err != nil {
+		return err
+	}
+	oldRequests, oldRate, err := revisionErrorRate(t, previous, since)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  %s: %.2f%% errors in %d requests, %s: %.2f%% in %d\n",
+		candidate, newRate, newRequests, previous, oldRate, oldRequests)
+	if newRequests < rolloutMinReqs {
+		fmt.Printf("  ⚠ Fewer than %d requests, error rates not compared\n", rolloutMinReqs)
+		return nil
+	}
+	if newRate-oldRate > rolloutErrorThreshold {
+		return fmt.Errorf("error rate of %s is %.2f points above %s", candidate, newRate-oldRate, previous)
+	}
+	fmt.Println("  ✓ No error rate regression")
+	return nil
+}
+
+// smokeTest requests url until it answers with the wanted status, giving up
+// after smokeAttempts attempts.
+func smokeTest(url, want string) error {
+	var last error
+	for attempt := 1; attempt <= smokeAttempts; attempt++ {
+		resp, err := smokeClient.Get(url)
+		if err != nil {
+			last = err
+		} else {
+			_ = resp.Body.Close()
+			if strconv.Itoa(resp.StatusCode) == want {
+				return nil
+			}
+			last = fmt.Errorf("GET %s returned %d, expected %s", url, resp.StatusCode, want)
+		}
+		fmt.Printf("  %v (attempt %d)\n", last, attempt)
+		if attempt < smokeAttempts {
+			time.Sleep(smokeRetryDelay)
+		}
+	}
+	return fmt.Errorf("smoke test failed: %w", last)
+}
+
+// revisionErrorRate returns the number of requests a revision served since a
+// time and the percentage of them that failed with a 5xx status, from the
+// Cloud Run request logs.
+func revisionErrorRate(t deployTarget, revision string, since time.Time) (int, float64, error) {
+	filter := fmt.Sprintf(`resource.type="cloud_run_revision" AND resource.labels.service_name=%q `+
+		`AND resource.labels.revision_name=%q AND httpRequest.status>0 AND timestamp>=%q`,
+		t.Service, revision, since.UTC().Format(time.RFC3339))
+	output, err := exec.Command("gcloud", "logging", "read", filter, "--project="+t.ProjectID,
+		"--limit=10000", "--format=value(httpRequest.status)").Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read the request logs of %s: %w", revision, err)
+	}
+
+	requests, errors := 0, 0
+	for _, line := range strings.Fields(string(output)) {
+		status, err := strconv.Atoi(line)
+		if err != nil {
+			continue
+		}
+		requests++
+		if status >= 500 {
+			errors++
+		}
+	}
+	if requests == 0 {
+		return 0, 0, nil
+	}
+	return requests, 100 * float64(errors) / float64(requests), nil
+}
@@ -17,10 +17,23 @@ var rootCmd = &cobra.Command{
 	Long: `A CLI tool to set up GCloud projects with GitHub Actions CI/CD.
 
 Commands:
-  gcsetup init                - Initialize local project files (workflows, .env template)
-  gcsetup project create      - Create a new GCP project and infrastructure
-  gcsetup service setup       - Configure service deployment in existing GCP project
-  gcsetup loadbalancer setup  - Configure a load balancer for multiple services`,
+  gcsetup init                  - Initialize local project files (workflows, .env template)
+  gcsetup templates list        - List the language templates for the deploy workflow
+  gcsetup workflow upgrade      - Merge template changes into the generated workflow
+  gcsetup project create        - Create a new GCP project and infrastructure
+  gcsetup service setup         - Configure service deployment in existing GCP project
+  gcsetup deployments           - List recent Cloud Run revisions with commit and digest
+  gcsetup rollout               - Move traffic to a new revision in steps, rolling back on errors
+  gcsetup rollback              - Route all traffic back to a previous revision
+  gcsetup repo add              - Allow another repository to deploy to the project
+  gcsetup repo remove           - Revoke the access of an added repository
+  gcsetup repo list             - List the repositories allowed to deploy
+  gcsetup loadbalancer setup    - Configure a load balancer for multiple services
+  gcsetup loadbalancer update   - Add or remove services, path rules and certificates
+  gcsetup loadbalancer shift    - Progressively shift path traffic between services
+  gcsetup loadbalancer import   - Import an existing load balancer into a spec file
+  gcsetup loadbalancer describe - Show the live load balancer topology
+  gcsetup loadbalancer delete   - Delete a load balancer and its resources`,
 }
 
 func Execute() {
@@ -88,8 +101,18 @@ var RequiredVars = []string{
 }
 
 func ValidateConfig() error {
+	required := RequiredVars
+	if ciProviderName() == "gitlab" {
+		required = []string{"GCP_GITLAB_PROJECT"}
+		for _, v := range RequiredVars {
+			if !strings.HasPrefix(v, "GCP_GITHUB_") {
+				required = append(required, v)
+			}
+		}
+	}
+
 	var missing []string
-	for _, v := range RequiredVars {
+	for _, v := range required {
 		if viper.GetString(v) == "" {
 			missing = append(missing, v)
 		}
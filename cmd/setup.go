@@ -19,17 +19,24 @@ var serviceCmd = &cobra.Command{
 	Long: `Configure a service for deployment in an existing GCP project:
   1. Set up Workload Identity Federation for GitHub
   2. Configure GitHub repository secrets and variables
-  3. Create deployment environments`,
+     (or GitLab CI/CD variables when GCP_CI_PROVIDER=gitlab)
+  3. Create deployment environments
+
+The GCP_WIF_* policy applies to all environments; GCP_<ENV>_WIF_REFS
+restricts the refs of jobs running in one environment, e.g.
+GCP_PRODUCTION_WIF_REFS=main.`,
 	RunE: runService,
 }
 
 var dryRun bool
 var nonInteractive bool
+var forceSecrets bool
 
 func init() {
 	rootCmd.AddCommand(serviceCmd)
 	serviceCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print commands without executing")
 	serviceCmd.Flags().BoolVarP(&nonInteractive, "yes", "y", false, "Non-interactive mode (accept all defaults)")
+	serviceCmd.Flags().BoolVar(&forceSecrets, "force", false, "Overwrite existing CI secrets")
 }
 
 func runService(cmd *cobra.Command, args []string) error {
@@ -37,7 +44,12 @@ func runService(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := checkGH(); err != nil {
+	name, err := detectCIProvider()
+	if err != nil {
+		return err
+	}
+	provider := ciProviderFor(name)
+	if err := provider.check(); err != nil {
 		return err
 	}
 
@@ -56,6 +68,12 @@ func runService(cmd *cobra.Command, args []string) error {
 	}
 
 	cfg := loadConfig()
+	if err := cfg.WIF.validate(); err != nil {
+		return err
+	}
+	if err := loadEnvironments(&cfg, dryRun); err != nil {
+		return err
+	}
 
 	fmt.Println()
 	fmt.Println("==============================================")
@@ -63,10 +81,15 @@ func runService(cmd *cobra.Command, args []string) error {
 	fmt.Println("==============================================")
 	fmt.Printf("  GCP Project ID:       %s\n", cfg.ProjectID)
 	fmt.Printf("  GCP Project Number:   %s\n", cfg.ProjectNumber)
-	fmt.Printf("  GitHub:               %s/%s\n", cfg.GitHubOrg, cfg.GitHubRepo)
+	fmt.Printf("  %s\n", ciPlatformLabel(cfg))
 	fmt.Printf("  Service Account:      %s\n", cfg.ServiceAccountName)
 	fmt.Printf("  Artifact Registry:    %s (%s)\n", cfg.ArtifactRegistryName, cfg.ArtifactRegistryLocation)
 	fmt.Printf("  Cloud Run Service:    %s (%s)\n", cfg.CloudRunService, cfg.CloudRunRegion)
+	fmt.Printf("  WIF Condition:        %s\n", cfg.WIF.attributeCondition())
+	for _, env := range cfg.Environments {
+		fmt.Printf("  %-21s %s in %s (%s)\n", strings.ToUpper(env.Name[:1])+env.Name[1:]+":",
+			env.CloudRunService, env.ProjectID, env.CloudRunRegion)
+	}
 	fmt.Println("==============================================")
 	fmt.Println()
 
@@ -78,13 +101,22 @@ func runService(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	steps := []struct {
+	type setupStep struct {
 		name string
 		fn   func(Config) error
-	}{
+	}
+	steps := []setupStep{
 		{"Setting up Workload Identity Federation", setupWorkloadIdentity},
-		{"Configuring GitHub Repository", configureGitHub},
 	}
+	for _, env := range cfg.Environments {
+		if env.hasOwnProject(cfg) {
+			steps = append(steps, setupStep{
+				"Setting up " + env.ProjectID + " for " + env.Name,
+				func(cfg Config) error { return setupEnvironmentProject(cfg, env) },
+			})
+		}
+	}
+	steps = append(steps, setupStep{"Configuring " + provider.name(), provider.configure})
 
 	for i, step := range steps {
 		fmt.Printf("Step %d/%d: %s...\n", i+1, len(steps), step.name)
@@ -114,8 +146,12 @@ func runService(cmd *cobra.Command, args []string) error {
 type Config struct {
 	ProjectID                string
 	ProjectNumber            string
+	CIProvider               string
+	GitHubHost               string
 	GitHubOrg                string
 	GitHubRepo               string
+	GitLabURL                string
+	GitLabProject            string
 	ServiceAccountName       string
 	ServiceAccountEmail      string
 	ArtifactRegistryName     string
@@ -124,6 +160,11 @@ type Config struct {
 	CloudRunRegion           string
 	WorkloadIdentityProvider string
 	ArtifactRegistryURL      string
+	WIF                      WIFPolicy
+	Environments             []Environment
+	Protection               map[string]EnvironmentProtection
+	BranchProtection         bool
+	RequiredApprovals        int
 }
 
 func saveConfig(cfg Config) error {
@@ -134,9 +175,19 @@ func saveConfig(cfg Config) error {
 GCP_PROJECT_ID=%s
 GCP_PROJECT_NUMBER=%s
 
+# CI provider (github or gitlab)
+GCP_CI_PROVIDER=%s
+
 # GitHub Repository
+GCP_GITHUB_HOST=%s
 GCP_GITHUB_ORGANIZATION=%s
 GCP_GITHUB_REPOSITORY=%s
+GCP_GITHUB_BRANCH_PROTECTION=%t
+GCP_GITHUB_REQUIRED_APPROVALS=%d
+
+# GitLab Project
+GCP_GITLAB_URL=%s
+GCP_GITLAB_PROJECT=%s
 
 # Service Account
 GCP_SERVICE_ACCOUNT_NAME=%s
@@ -149,24 +200,41 @@ GCP_ARTIFACT_REGISTRY_LOCATION=%s
 GCP_CLOUD_RUN_SERVICE=%s
 GCP_REGION=%s
 
+# Workload Identity policy
+GCP_WIF_REPOSITORIES=%s
+GCP_WIF_REFS=%s
+GCP_WIF_ENVIRONMENTS=%s
+GCP_WIF_WORKFLOWS=%s
+
 # Computed values (for reference)
 # GCP_SERVICE_ACCOUNT_EMAIL=%s
 # GCP_WORKLOAD_IDENTITY_PROVIDER=%s
 # GCP_ARTIFACT_REGISTRY_URL=%s
-`,
+%s`,
 		time.Now().Format(time.RFC3339),
 		cfg.ProjectID,
 		cfg.ProjectNumber,
+		cfg.CIProvider,
+		cfg.GitHubHost,
 		cfg.GitHubOrg,
 		cfg.GitHubRepo,
+		cfg.BranchProtection,
+		cfg.RequiredApprovals,
+		cfg.GitLabURL,
+		cfg.GitLabProject,
 		cfg.ServiceAccountName,
 		cfg.ArtifactRegistryName,
 		cfg.ArtifactRegistryLocation,
 		cfg.CloudRunService,
 		cfg.CloudRunRegion,
+		strings.Join(cfg.WIF.Repositories, ","),
+		strings.Join(cfg.WIF.Refs, ","),
+		strings.Join(cfg.WIF.Environments, ","),
+		strings.Join(cfg.WIF.Workflows, ","),
 		cfg.ServiceAccountEmail,
 		cfg.WorkloadIdentityProvider,
 		cfg.ArtifactRegistryURL,
+		environmentConfigLines(),
 	)
 
 	if err := os.WriteFile(".env.gcloud.local", []byte(content), 0644); err != nil {
@@ -210,23 +278,35 @@ func loadConfig() Config {
 	arLocation := viper.GetString("GCP_ARTIFACT_REGISTRY_LOCATION")
 	arName := viper.GetString("GCP_ARTIFACT_REGISTRY_NAME")
 
-	return Config{
+	cfg := Config{
 		ProjectID:                projectID,
 		ProjectNumber:            projectNumber,
+		CIProvider:               ciProviderName(),
+		GitHubHost:               githubHost(),
 		GitHubOrg:                viper.GetString("GCP_GITHUB_ORGANIZATION"),
 		GitHubRepo:               viper.GetString("GCP_GITHUB_REPOSITORY"),
+		GitLabURL:                gitlabURL(),
+		GitLabProject:            viper.GetString("GCP_GITLAB_PROJECT"),
 		ServiceAccountName:       saName,
 		ServiceAccountEmail:      fmt.Sprintf("%s@%s.iam.gserviceaccount.com", saName, projectID),
 		ArtifactRegistryName:     arName,
 		ArtifactRegistryLocation: arLocation,
 		CloudRunService:          viper.GetString("GCP_CLOUD_RUN_SERVICE"),
 		CloudRunRegion:           viper.GetString("GCP_REGION"),
-		WorkloadIdentityProvider: fmt.Sprintf(
-			"projects/%s/locations/global/workloadIdentityPools/github-pool/providers/github-provider",
-			projectNumber,
-		),
-		ArtifactRegistryURL: fmt.Sprintf("%s-docker.pkg.dev/%s/%s", arLocation, projectID, arName),
+		ArtifactRegistryURL:      fmt.Sprintf("%s-docker.pkg.dev/%s/%s", arLocation, projectID, arName),
+		BranchProtection:         viper.GetBool("GCP_GITHUB_BRANCH_PROTECTION"),
+		RequiredApprovals:        1,
 	}
+	if viper.GetString("GCP_GITHUB_REQUIRED_APPROVALS") != "" {
+		cfg.RequiredApprovals = viper.GetInt("GCP_GITHUB_REQUIRED_APPROVALS")
+	}
+	if cfg.CIProvider == "gitlab" {
+		cfg.WIF = loadWIFPolicy(gitlabIssuer(cfg.GitLabURL), gitlabNamespace(cfg.GitLabProject), cfg.GitLabProject)
+	} else {
+		cfg.WIF = loadWIFPolicy(githubIssuer(cfg.GitHubHost), cfg.GitHubOrg, cfg.GitHubRepo)
+	}
+	cfg.WorkloadIdentityProvider = cfg.WIF.Issuer.providerName(projectNumber)
+	return cfg
 }
 
 var reader = bufio.NewReader(os.Stdin)
@@ -409,31 +489,13 @@ func interactiveConfig() error {
 	viper.Set("GCP_PROJECT_ID", projectID)
 	viper.Set("GCP_PROJECT_NUMBER", projectNumber)
 
-	fmt.Println()
-	fmt.Println("── GitHub Repository ────────────────────────")
-	gitHubOrg := viper.GetString("GCP_GITHUB_ORGANIZATION")
-	gitHubRepo := viper.GetString("GCP_GITHUB_REPOSITORY")
-
-	if gitHubOrg == "" || gitHubRepo == "" {
-		cmd := exec.Command("git", "remote", "get-url", "origin")
-		output, err := cmd.Output()
-		if err == nil {
-			detectedOrg, detectedRepo := parseGitRemote(strings.TrimSpace(string(output)))
-			if gitHubOrg == "" {
-				gitHubOrg = detectedOrg
-			}
-			if gitHubRepo == "" {
-				gitHubRepo = detectedRepo
-			}
-		}
+	var defaultService string
+	if ciProviderName() == "gitlab" {
+		defaultService = interactiveGitLabConfig()
+	} else {
+		defaultService = interactiveGitHubConfig()
 	}
 
-	gitHubOrg = prompt("GCP_GITHUB_ORGANIZATION", gitHubOrg)
-	viper.Set("GCP_GITHUB_ORGANIZATION", gitHubOrg)
-
-	gitHubRepo = prompt("GCP_GITHUB_REPOSITORY", gitHubRepo)
-	viper.Set("GCP_GITHUB_REPOSITORY", gitHubRepo)
-
 	fmt.Println()
 	fmt.Println("── Cloud Run ────────────────────────────────")
 
@@ -447,7 +509,6 @@ func interactiveConfig() error {
 	}
 	viper.Set("GCP_REGION", cloudRunRegion)
 
-	defaultService := gitHubRepo
 	cloudRunService := prompt("GCP_CLOUD_RUN_SERVICE", defaultService)
 	viper.Set("GCP_CLOUD_RUN_SERVICE", cloudRunService)
 
@@ -467,27 +528,74 @@ func interactiveConfig() error {
 	return nil
 }
 
-func parseGitRemote(url string) (org, repo string) {
+// interactiveGitHubConfig prompts for the GitHub repository and returns its
+// name.
+func interactiveGitHubConfig() string {
+	fmt.Println()
+	fmt.Println("── GitHub Repository ────────────────────────")
+	gitHubOrg := viper.GetString("GCP_GITHUB_ORGANIZATION")
+	gitHubRepo := viper.GetString("GCP_GITHUB_REPOSITORY")
 
-	if strings.HasPrefix(url, "git@github.com:") {
-		path := strings.TrimPrefix(url, "git@github.com:")
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.Split(path, "/")
-		if len(parts) == 2 {
-			return parts[0], parts[1]
+	if gitHubOrg == "" || gitHubRepo == "" {
+		cmd := exec.Command("git", "remote", "get-url", "origin")
+		output, err := cmd.Output()
+		if err == nil {
+			detectedOrg, detectedRepo := parseGitRemote(strings.TrimSpace(string(output)))
+			if gitHubOrg == "" {
+				gitHubOrg = detectedOrg
+			}
+			if gitHubRepo == "" {
+				gitHubRepo = detectedRepo
+			}
 		}
 	}
 
-	if strings.Contains(url, "github.com/") {
-		idx := strings.Index(url, "github.com/")
-		path := url[idx+len("github.com/"):]
-		path = strings.TrimSuffix(path, ".git")
-		parts := strings.Split(path, "/")
-		if len(parts) >= 2 {
-			return parts[0], parts[1]
+	gitHubOrg = prompt("GCP_GITHUB_ORGANIZATION", gitHubOrg)
+	viper.Set("GCP_GITHUB_ORGANIZATION", gitHubOrg)
+
+	gitHubRepo = prompt("GCP_GITHUB_REPOSITORY", gitHubRepo)
+	viper.Set("GCP_GITHUB_REPOSITORY", gitHubRepo)
+
+	protection := viper.GetString("GCP_GITHUB_BRANCH_PROTECTION")
+	if protection == "" {
+		protection = "false"
+	}
+	viper.Set("GCP_GITHUB_BRANCH_PROTECTION", prompt("GCP_GITHUB_BRANCH_PROTECTION", protection))
+	return gitHubRepo
+}
+
+// interactiveGitLabConfig prompts for the GitLab instance and project and
+// returns the project name.
+func interactiveGitLabConfig() string {
+	fmt.Println()
+	fmt.Println("── GitLab Project ───────────────────────────")
+	gitLabURL := prompt("GCP_GITLAB_URL", gitlabURL())
+	viper.Set("GCP_GITLAB_URL", gitLabURL)
+
+	gitLabProject := viper.GetString("GCP_GITLAB_PROJECT")
+	if gitLabProject == "" {
+		if host, path := originRemote(); isGitLabHost(host) {
+			gitLabProject = path
 		}
 	}
+	gitLabProject = prompt("GCP_GITLAB_PROJECT", gitLabProject)
+	viper.Set("GCP_GITLAB_PROJECT", gitLabProject)
+	viper.Set("GCP_CI_PROVIDER", "gitlab")
 
+	return gitLabProject[strings.LastIndex(gitLabProject, "/")+1:]
+}
+
+// parseGitRemote returns the owner and repository of a GitHub remote on
+// github.com, GHE.com or the configured GitHub Enterprise Server host.
+func parseGitRemote(url string) (org, repo string) {
+	host, path := parseRemoteURL(url)
+	if !isGitHubHost(host) {
+		return "", ""
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) >= 2 {
+		return parts[0], parts[1]
+	}
 	return "", ""
 }
 
@@ -531,19 +639,12 @@ func checkGcloud() error {
 	return nil
 }
 
-func checkGH() error {
-	if _, err := exec.LookPath("gh"); err != nil {
-		return fmt.Errorf("GitHub CLI (gh) not found. Please install it: https://cli.github.com")
-	}
-	cmd := exec.Command("gh", "auth", "status")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("GitHub CLI not authenticated. Run: gh auth login")
-	}
-	return nil
+func runCommandSilent(args ...string) error {
+	return runGcloud(dryRun, args...)
 }
 
-func runCommandSilent(args ...string) error {
-	if dryRun {
+func runGcloud(dry bool, args ...string) error {
+	if dry {
 		fmt.Printf("  [dry-run] gcloud %s\n", strings.Join(args, " "))
 		return nil
 	}
@@ -555,201 +656,26 @@ func runCommandSilent(args ...string) error {
 	return nil
 }
 
-func setupWorkloadIdentity(cfg Config) error {
-	fmt.Println("  Checking Workload Identity Pool status...")
-	poolState := getPoolState(cfg.ProjectID, "github-pool")
-
-	switch poolState {
-	case "DELETED":
-		fmt.Println("  Pool is soft-deleted, restoring...")
-		if err := runCommandSilent("iam", "workload-identity-pools", "undelete", "github-pool",
-			"--project="+cfg.ProjectID,
-			"--location=global",
-		); err != nil {
-			return fmt.Errorf("failed to restore workload identity pool: %w", err)
-		}
-		fmt.Println("  Pool restored successfully")
-	case "ACTIVE":
-		fmt.Println("  Pool already exists and is active")
-	case "NOT_FOUND":
-		fmt.Println("  Creating new pool...")
-		if err := runCommandSilent("iam", "workload-identity-pools", "create", "github-pool",
-			"--project="+cfg.ProjectID,
-			"--location=global",
-			"--display-name=GitHub Actions Pool",
-		); err != nil {
-			return fmt.Errorf("failed to create workload identity pool: %w", err)
-		}
-		fmt.Println("  Pool created successfully")
-	}
-
-	fmt.Println("  Waiting for pool to be ready...")
-	time.Sleep(5 * time.Second)
-
-	fmt.Println("  Checking OIDC Provider status...")
-	providerState := getProviderState(cfg.ProjectID, "github-pool", "github-provider")
-
-	switch providerState {
-	case "DELETED":
-		fmt.Println("  Provider is soft-deleted, restoring...")
-		if err := runCommandSilent("iam", "workload-identity-pools", "providers", "undelete", "github-provider",
-			"--project="+cfg.ProjectID,
-			"--location=global",
-			"--workload-identity-pool=github-pool",
-		); err != nil {
-			return fmt.Errorf("failed to restore OIDC provider: %w", err)
-		}
-		fmt.Println("  Provider restored successfully")
-	case "ACTIVE":
-		fmt.Println("  Provider already exists and is active")
-	case "NOT_FOUND":
-		fmt.Println("  Creating new provider...")
-		attrMapping := "google.subject=assertion.sub," +
-			"attribute.actor=assertion.actor," +
-			"attribute.repository=assertion.repository," +
-			"attribute.repository_owner=assertion.repository_owner"
-		attrCondition := fmt.Sprintf("assertion.repository_owner == '%s'", cfg.GitHubOrg)
-
-		if err := runCommandSilent("iam", "workload-identity-pools", "providers", "create-oidc", "github-provider",
-			"--project="+cfg.ProjectID,
-			"--location=global",
-			"--workload-identity-pool=github-pool",
-			"--display-name=GitHub Provider",
-			"--issuer-uri=https://token.actions.githubusercontent.com",
-			"--attribute-mapping="+attrMapping,
-			"--attribute-condition="+attrCondition,
-		); err != nil {
-			return fmt.Errorf("failed to create OIDC provider: %w", err)
-		}
-		fmt.Println("  Provider created successfully")
-	}
-
-	fmt.Println("  Configuring repository access...")
-	memberFmt := "principalSet://iam.googleapis.com/projects/%s/locations/global/" +
-		"workloadIdentityPools/github-pool/attribute.repository/%s/%s"
-	member := fmt.Sprintf(memberFmt, cfg.ProjectNumber, cfg.GitHubOrg, cfg.GitHubRepo)
-
-	return runCommandSilent("iam", "service-accounts", "add-iam-policy-binding", cfg.ServiceAccountEmail,
-		"--project="+cfg.ProjectID,
-		"--role=roles/iam.workloadIdentityUser",
-		"--member="+member,
-	)
-}
-
-func getPoolState(projectID, poolID string) string {
-	if dryRun {
-		return "NOT_FOUND"
-	}
-	cmd := exec.Command("gcloud", "iam", "workload-identity-pools", "describe", poolID,
-		"--project="+projectID,
-		"--location=global",
-		"--format=value(state)",
-	)
+func gcloudJSON(v any, args ...string) error {
+	cmd := exec.Command("gcloud", append(args, "--format=json")...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
 	output, err := cmd.Output()
 	if err != nil {
-		return "NOT_FOUND"
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
 	}
-	state := strings.TrimSpace(string(output))
-	if state == "DELETED" {
-		return "DELETED"
-	}
-	return "ACTIVE"
+	return json.Unmarshal(output, v)
 }
 
-func getProviderState(projectID, poolID, providerID string) string {
-	if dryRun {
-		return "NOT_FOUND"
-	}
-	cmd := exec.Command("gcloud", "iam", "workload-identity-pools", "providers", "describe", providerID,
-		"--project="+projectID,
-		"--location=global",
-		"--workload-identity-pool="+poolID,
-		"--format=value(state)",
-	)
-	output, err := cmd.Output()
-	if err != nil {
-		return "NOT_FOUND"
-	}
-	state := strings.TrimSpace(string(output))
-	if state == "DELETED" {
-		return "DELETED"
-	}
-	return "ACTIVE"
+func gcloudExists(args ...string) bool {
+	return exec.Command("gcloud", args...).Run() == nil
 }
 
-func configureGitHub(cfg Config) error {
-	repo := fmt.Sprintf("%s/%s", cfg.GitHubOrg, cfg.GitHubRepo)
-
-	fmt.Println("  Setting secrets...")
-	secrets := map[string]string{
-		"GCP_SERVICE_ACCOUNT":            cfg.ServiceAccountEmail,
-		"GCP_WORKLOAD_IDENTITY_PROVIDER": cfg.WorkloadIdentityProvider,
-	}
-
-	for name, value := range secrets {
-		if dryRun {
-			fmt.Printf("    [dry-run] gh secret set %s --repo %s\n", name, repo)
-			continue
-		}
-
-		cmd := exec.Command("gh", "secret", "list", "--repo", repo)
-		output, _ := cmd.Output()
-		if strings.Contains(string(output), name) {
-			fmt.Printf("    %s (already set, skipping assignment)\n", name)
-			continue
-		}
-		fmt.Printf("    %s\n", name)
-		cmd = exec.Command("gh", "secret", "set", name, "--repo", repo, "--body", value)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to set secret %s: %w", name, err)
-		}
-	}
-
-	fmt.Println("  Setting variables...")
-	variables := map[string]string{
-		"GCP_PROJECT_ID":        cfg.ProjectID,
-		"GCP_REGION":            cfg.CloudRunRegion,
-		"GCP_CLOUD_RUN_SERVICE": cfg.CloudRunService,
-		"GCP_ARTIFACT_REGISTRY": cfg.ArtifactRegistryName,
-	}
-
-	for name, value := range variables {
-		if dryRun {
-			fmt.Printf("    [dry-run] gh variable set %s --repo %s\n", name, repo)
-			continue
-		}
-
-		cmd := exec.Command("gh", "variable", "list", "--repo", repo)
-		output, _ := cmd.Output()
-		if strings.Contains(string(output), name) {
-			fmt.Printf("    %s (already set, skipping assignment)\n", name)
-			continue
-		}
-		fmt.Printf("    %s\n", name)
-		cmd = exec.Command("gh", "variable", "set", name, "--repo", repo, "--body", value)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to set variable %s: %w", name, err)
-		}
-	}
-
-	fmt.Println("  Creating environments...")
-	environments := []string{"development", "staging", "production", "preview"}
-
-	for _, env := range environments {
-		fmt.Printf("    %s\n", env)
-		if dryRun {
-			fmt.Printf("    [dry-run] gh api repos/%s/environments/%s -X PUT\n", repo, env)
-			continue
-		}
-		cmd := exec.Command("gh", "api", fmt.Sprintf("repos/%s/environments/%s", repo, env), "-X", "PUT")
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("    ⚠ Could not create environment %s (may require admin access)\n", env)
-		}
-	}
-
-	fmt.Println()
-	fmt.Println("  💡 Tip: Add protection rules in GitHub → Settings → Environments")
-	fmt.Println("     For 'production': require reviewers before deployment")
-
-	return nil
+func setupWorkloadIdentity(cfg Config) error {
+	return ensureWorkloadIdentity(dryRun, wifTarget{
+		ProjectID:           cfg.ProjectID,
+		ProjectNumber:       cfg.ProjectNumber,
+		ServiceAccountEmail: cfg.ServiceAccountEmail,
+		Policy:              cfg.WIF,
+	})
 }
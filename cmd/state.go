@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stateDir holds repository-local gcsetup files. Unlike .env.gcloud it is
+// meant to be committed.
+const stateDir = ".gcsetup"
+
+// State records the resources gcsetup manages for this repository.
+type State struct {
+	LoadBalancers map[string]*LoadBalancerState `json:"loadBalancers,omitempty"`
+	// SecretHashes maps a secret's API path to an HMAC-SHA256 of the value
+	// gcsetup last set, so changed values can be detected. The key is kept
+	// out of version control in secretHashKeyPath.
+	SecretHashes map[string]string `json:"secretHashes,omitempty"`
+	// Workflows maps a generated CI file to the template it was rendered from.
+	Workflows map[string]*WorkflowState `json:"workflows,omitempty"`
+}
+
+// WorkflowState records how a CI file was generated, so template upgrades can
+// be merged with the edits made to it since. Base is a copy of the file as
+// rendered, kept under stateDir.
+type WorkflowState struct {
+	CI         string `json:"ci"`
+	Template   string `json:"template"`
+	Version    string `json:"version"`
+	Base       string `json:"base"`
+	RenderedAt string `json:"renderedAt,omitempty"`
+}
+
+// LoadBalancerState records the spec file and the actual resource names of a
+// load balancer, which differ from the naming scheme for imported ones.
+type LoadBalancerState struct {
+	Spec                  string   `json:"spec,omitempty"`
+	URLMap                string   `json:"urlMap,omitempty"`
+	Proxy                 string   `json:"proxy,omitempty"`
+	ForwardingRule        string   `json:"forwardingRule,omitempty"`
+	Address               string   `json:"address,omitempty"`
+	ForwardingRuleIPv6    string   `json:"forwardingRuleIPv6,omitempty"`
+	AddressIPv6           string   `json:"addressIPv6,omitempty"`
+	BackendServices       []string `json:"backendServices,omitempty"`
+	NetworkEndpointGroups []string `json:"networkEndpointGroups,omitempty"`
+	ImportedAt            string   `json:"importedAt,omitempty"`
+}
+
+func statePath() string {
+	return filepath.Join(stateDir, "state.json")
+}
+
+// loadState reads the state file, returning an empty state if there is none.
+func loadState() (*State, error) {
+	st := &State{}
+	data, err := os.ReadFile(statePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", statePath(), err)
+	}
+	return st, nil
+}
+
+func saveState(st *State) error {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(statePath(), append(data, '\n'), 0644)
+}
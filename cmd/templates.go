@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"gcsetup/embedded"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Manage the language templates of the deploy workflow",
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the language templates init can use",
+	RunE:  runTemplatesList,
+}
+
+func init() {
+	rootCmd.AddCommand(templatesCmd)
+	templatesCmd.AddCommand(templatesListCmd)
+}
+
+// languageMarkers maps a file in the project root to the language template it
+// selects, in detection order.
+var languageMarkers = []struct {
+	file     string
+	template string
+}{
+	{"go.mod", "go"},
+	{"package.json", "node"},
+	{"pyproject.toml", "python"},
+	{"pom.xml", "java"},
+	{"Gemfile", "ruby"},
+}
+
+// detectLanguage returns the template for the project in dir, or "generic".
+func detectLanguage(dir string) string {
+	for _, m := range languageMarkers {
+		if _, err := os.Stat(filepath.Join(dir, m.file)); err == nil {
+			return m.template
+		}
+	}
+	return "generic"
+}
+
+// languageTemplate is a template and the description from its leading comment.
+type languageTemplate struct {
+	Name        string
+	Description string
+	Source      []byte
+}
+
+var descriptionPattern = regexp.MustCompile(`^\{%-?\s*/\*\s*(.*?)\s*\*/`)
+
+func languageTemplates() ([]languageTemplate, error) {
+	entries, err := fs.ReadDir(embedded.Templates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	var templates []languageTemplate
+	for _, e := range entries {
+		src, err := fs.ReadFile(embedded.Templates, "templates/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		t := languageTemplate{Name: strings.TrimSuffix(e.Name(), ".yml"), Source: src}
+		if m := descriptionPattern.FindSubmatch(src); m != nil {
+			t.Description = string(m[1])
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func findLanguageTemplate(name string) (languageTemplate, error) {
+	templates, err := languageTemplates()
+	if err != nil {
+		return languageTemplate{}, err
+	}
+	var names []string
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	return languageTemplate{}, fmt.Errorf("unknown template %q, available: %s", name, strings.Join(names, ", "))
+}
+
+// templateDirs returns the directories searched for template overrides, in
+// priority order: the repository's, GCP_TEMPLATE_DIR (e.g. a checkout shared
+// by an organization) and the user's.
+func templateDirs() []string {
+	dirs := []string{filepath.Join(stateDir, "templates")}
+	if dir := viper.GetString("GCP_TEMPLATE_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "gcsetup", "templates"))
+	}
+	return dirs
+}
+
+// resolvedTemplate is a template and the overlays redefining its blocks, in
+// parse order. Files lists the override files used.
+type resolvedTemplate struct {
+	Source   []byte
+	Overlays [][]byte
+	Files    []string
+}
+
+// resolveTemplate looks up the template name in the template directories. A
+// file that only defines blocks extends the template further down the search
+// path, ending with builtin; any other file replaces it.
+func resolveTemplate(name string, builtin []byte) (resolvedTemplate, error) {
+	r := resolvedTemplate{Source: builtin}
+	for _, dir := range templateDirs() {
+		path := filepath.Join(dir, name)
+		src, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return r, err
+		}
+		r.Files = append(r.Files, path)
+
+		extends, err := onlyBlocks(path, src)
+		if err != nil {
+			return r, err
+		}
+		if !extends {
+			r.Source = src
+			break
+		}
+		r.Overlays = append([][]byte{src}, r.Overlays...)
+	}
+	return r, nil
+}
+
+// onlyBlocks reports whether a template consists of define blocks only.
+func onlyBlocks(name string, src []byte) (bool, error) {
+	var t *template.Template
+	t = template.New(name).Delims("{%", "%}").Funcs(templateFuncs(&t))
+	if _, err := t.Parse(string(src)); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return t.Tree == nil || parse.IsEmptyTree(t.Tree.Root), nil
+}
+
+// envTemplate returns the .env.gcloud template, the first one found in the
+// template directories or the built-in one.
+func envTemplate() ([]byte, string, error) {
+	for _, dir := range templateDirs() {
+		path := filepath.Join(dir, "env.gcloud.template")
+		src, err := os.ReadFile(path)
+		if err == nil {
+			return src, path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	return embedded.EnvTemplate, "", nil
+}
+
+func runTemplatesList(cmd *cobra.Command, args []string) error {
+	templates, err := languageTemplates()
+	if err != nil {
+		return err
+	}
+	detected := detectLanguage(".")
+	for _, t := range templates {
+		marker := " "
+		if t.Name == detected {
+			marker = "*"
+		}
+		fmt.Printf("%s %-10s %s\n", marker, t.Name, t.Description)
+	}
+	fmt.Println()
+	fmt.Println("* detected for this project; use gcsetup init --template <name> to override")
+
+	fmt.Println()
+	fmt.Println("Override directories, highest priority first:")
+	for _, dir := range templateDirs() {
+		var found []string
+		for _, name := range []string{"gcloud-deploy.yml", "gitlab-ci.yml", "env.gcloud.template"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				found = append(found, name)
+			}
+		}
+		if len(found) == 0 {
+			found = []string{"(none)"}
+		}
+		fmt.Printf("  %s: %s\n", dir, strings.Join(found, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,454 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const computeAPIPrefix = "https://www.googleapis.com/compute/v1/"
+
+const defaultPathMatcher = "path-matcher-1"
+
+// urlMap mirrors the document produced by `gcloud compute url-maps export`.
+// Fields gcsetup does not manage are kept in Extra so they survive a round trip.
+type urlMap struct {
+	Name           string         `yaml:"name"`
+	DefaultService string         `yaml:"defaultService,omitempty"`
+	HostRules      []hostRule     `yaml:"hostRules,omitempty"`
+	PathMatchers   []pathMatcher  `yaml:"pathMatchers,omitempty"`
+	Extra          map[string]any `yaml:",inline"`
+}
+
+type hostRule struct {
+	Hosts       []string       `yaml:"hosts"`
+	PathMatcher string         `yaml:"pathMatcher"`
+	Extra       map[string]any `yaml:",inline"`
+}
+
+type pathMatcher struct {
+	Name           string         `yaml:"name"`
+	DefaultService string         `yaml:"defaultService,omitempty"`
+	PathRules      []pathRule     `yaml:"pathRules,omitempty"`
+	RouteRules     []routeRule    `yaml:"routeRules,omitempty"`
+	Extra          map[string]any `yaml:",inline"`
+}
+
+type pathRule struct {
+	Paths   []string       `yaml:"paths"`
+	Service string         `yaml:"service,omitempty"`
+	Extra   map[string]any `yaml:",inline"`
+}
+
+// routeRule is the advanced counterpart of pathRule. A path matcher holds
+// either path rules or route rules, never both.
+type routeRule struct {
+	Priority    int            `yaml:"priority"`
+	MatchRules  []matchRule    `yaml:"matchRules,omitempty"`
+	Service     string         `yaml:"service,omitempty"`
+	RouteAction *routeAction   `yaml:"routeAction,omitempty"`
+	Extra       map[string]any `yaml:",inline"`
+}
+
+type matchRule struct {
+	PrefixMatch   string         `yaml:"prefixMatch,omitempty"`
+	FullPathMatch string         `yaml:"fullPathMatch,omitempty"`
+	Extra         map[string]any `yaml:",inline"`
+}
+
+type routeAction struct {
+	WeightedBackendServices []weightedBackendService `yaml:"weightedBackendServices,omitempty"`
+	Extra                   map[string]any           `yaml:",inline"`
+}
+
+type weightedBackendService struct {
+	BackendService string         `yaml:"backendService"`
+	Weight         int            `yaml:"weight"`
+	Extra          map[string]any `yaml:",inline"`
+}
+
+func backendServiceURL(projectID, backend string) string {
+	return fmt.Sprintf("%sprojects/%s/global/backendServices/%s", computeAPIPrefix, projectID, backend)
+}
+
+// resourceName returns the last segment of a compute resource URL.
+func resourceName(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+func exportURLMap(projectID, name string) (*urlMap, error) {
+	cmd := exec.Command("gcloud", "compute", "url-maps", "export", name,
+		"--global", "--project="+projectID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export URL map %s: %s", name, strings.TrimSpace(stderr.String()))
+	}
+
+	m := &urlMap{}
+	if err := yaml.Unmarshal(output, m); err != nil {
+		return nil, fmt.Errorf("failed to parse URL map %s: %w", name, err)
+	}
+	return m, nil
+}
+
+func importURLMap(projectID string, m *urlMap) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if lbDryRun {
+		fmt.Printf("  [dry-run] gcloud compute url-maps import %s --global --project=%s\n", m.Name, projectID)
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+		return nil
+	}
+
+	cmd := exec.Command("gcloud", "compute", "url-maps", "import", m.Name,
+		"--global", "--quiet", "--project="+projectID)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to import URL map %s: %s", m.Name, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// matcher returns the path matcher used for all hosts, creating it if the map
+// has none yet. A new matcher gets a name no other matcher uses, or the name
+// a dangling * host rule already refers to.
+func (m *urlMap) matcher() *pathMatcher {
+	name := ""
+	for _, hr := range m.HostRules {
+		if slices.Contains(hr.Hosts, "*") {
+			name = hr.PathMatcher
+			for i := range m.PathMatchers {
+				if m.PathMatchers[i].Name == name {
+					return &m.PathMatchers[i]
+				}
+			}
+		}
+	}
+
+	if name == "" {
+		name = m.unusedMatcherName()
+		m.HostRules = append(m.HostRules, hostRule{Hosts: []string{"*"}, PathMatcher: name})
+	}
+	m.PathMatchers = append(m.PathMatchers, pathMatcher{
+		Name:           name,
+		DefaultService: m.DefaultService,
+	})
+	return &m.PathMatchers[len(m.PathMatchers)-1]
+}
+
+// unusedMatcherName returns defaultPathMatcher, or path-matcher-<n> for the
+// first n not taken by a path matcher or host rule.
+func (m *urlMap) unusedMatcherName() string {
+	taken := map[string]bool{}
+	for _, pm := range m.PathMatchers {
+		taken[pm.Name] = true
+	}
+	for _, hr := range m.HostRules {
+		taken[hr.PathMatcher] = true
+	}
+	name := defaultPathMatcher
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("path-matcher-%d", n)
+	}
+	return name
+}
+
+// setPathRule routes path to service, moving the path out of any other rule.
+func (m *urlMap) setPathRule(path, service string) {
+	pm := m.matcher()
+	pm.removePath(path)
+
+	if len(pm.RouteRules) > 0 {
+		pm.RouteRules = append(pm.RouteRules, routeRule{
+			MatchRules: []matchRule{pathToMatchRule(path)},
+			Service:    service,
+		})
+		pm.sortRouteRules()
+		return
+	}
+
+	for i := range pm.PathRules {
+		if resourceName(pm.PathRules[i].Service) == resourceName(service) {
+			pm.PathRules[i].Paths = append(pm.PathRules[i].Paths, path)
+			return
+		}
+	}
+	pm.PathRules = append(pm.PathRules, pathRule{Paths: []string{path}, Service: service})
+}
+
+// removePath drops path from every path matcher and reports whether it was found.
+func (m *urlMap) removePath(path string) bool {
+	found := false
+	for i := range m.PathMatchers {
+		if m.PathMatchers[i].removePath(path) {
+			found = true
+		}
+	}
+	return found
+}
+
+func (pm *pathMatcher) removePath(path string) bool {
+	found := false
+
+	var routes []routeRule
+	for _, rule := range pm.RouteRules {
+		if rule.path() == path {
+			found = true
+			continue
+		}
+		routes = append(routes, rule)
+	}
+	pm.RouteRules = routes
+
+	var rules []pathRule
+	for _, rule := range pm.PathRules {
+		var paths []string
+		for _, p := range rule.Paths {
+			if p == path {
+				found = true
+				continue
+			}
+			paths = append(paths, p)
+		}
+		if len(paths) > 0 {
+			rule.Paths = paths
+			rules = append(rules, rule)
+		}
+	}
+	pm.PathRules = rules
+	return found
+}
+
+// removeService drops every path rule pointing at service and returns the
+// number of rules removed.
+func (m *urlMap) removeService(service string) int {
+	removed := 0
+	for i := range m.PathMatchers {
+		pm := &m.PathMatchers[i]
+		var rules []pathRule
+		for _, rule := range pm.PathRules {
+			if resourceName(rule.Service) == resourceName(service) {
+				removed++
+				continue
+			}
+			rules = append(rules, rule)
+		}
+		pm.PathRules = rules
+
+		var routes []routeRule
+		for _, rule := range pm.RouteRules {
+			if resourceName(rule.Service) == resourceName(service) {
+				removed++
+				continue
+			}
+			if rule.RouteAction != nil && len(rule.RouteAction.WeightedBackendServices) > 0 {
+				var weighted []weightedBackendService
+				for _, wb := range rule.RouteAction.WeightedBackendServices {
+					if resourceName(wb.BackendService) != resourceName(service) {
+						weighted = append(weighted, wb)
+					}
+				}
+				if len(weighted) == 0 {
+					removed++
+					continue
+				}
+				if len(weighted) < len(rule.RouteAction.WeightedBackendServices) {
+					removed++
+					rule.RouteAction.WeightedBackendServices = weighted
+				}
+			}
+			routes = append(routes, rule)
+		}
+		pm.RouteRules = routes
+	}
+	return removed
+}
+
+// setWeightedRoute splits the traffic of path between the given backends.
+// Path rules of the matcher are converted to route rules first, because a
+// path matcher cannot hold both.
+func (m *urlMap) setWeightedRoute(path string, backends []weightedBackendService) {
+	pm := m.matcher()
+	pm.convertPathRules()
+
+	for i := range pm.RouteRules {
+		if pm.RouteRules[i].path() != path {
+			continue
+		}
+		if pm.RouteRules[i].RouteAction == nil {
+			pm.RouteRules[i].RouteAction = &routeAction{}
+		}
+		pm.RouteRules[i].Service = ""
+		pm.RouteRules[i].RouteAction.WeightedBackendServices = backends
+		return
+	}
+
+	pm.RouteRules = append(pm.RouteRules, routeRule{
+		MatchRules:  []matchRule{pathToMatchRule(path)},
+		RouteAction: &routeAction{WeightedBackendServices: backends},
+	})
+	pm.sortRouteRules()
+}
+
+// route returns the route rule matching path, or nil.
+func (m *urlMap) route(path string) *routeRule {
+	for i := range m.PathMatchers {
+		for j := range m.PathMatchers[i].RouteRules {
+			if m.PathMatchers[i].RouteRules[j].path() == path {
+				return &m.PathMatchers[i].RouteRules[j]
+			}
+		}
+	}
+	return nil
+}
+
+// serviceForPath returns the backend service a plain path or route rule
+// sends path to, or "" if the path is unknown or split between services.
+func (m *urlMap) serviceForPath(path string) string {
+	for _, pm := range m.PathMatchers {
+		for _, rule := range pm.PathRules {
+			for _, p := range rule.Paths {
+				if p == path {
+					return resourceName(rule.Service)
+				}
+			}
+		}
+	}
+	if r := m.route(path); r != nil && r.Service != "" {
+		return resourceName(r.Service)
+	}
+	return ""
+}
+
+func (pm *pathMatcher) convertPathRules() {
+	for _, rule := range pm.PathRules {
+		for _, p := range rule.Paths {
+			pm.RouteRules = append(pm.RouteRules, routeRule{
+				MatchRules: []matchRule{pathToMatchRule(p)},
+				Service:    rule.Service,
+			})
+		}
+	}
+	pm.PathRules = nil
+	pm.sortRouteRules()
+}
+
+// sortRouteRules orders route rules so that the most specific path wins, the
+// same way path rules are evaluated, and renumbers their priorities.
+func (pm *pathMatcher) sortRouteRules() {
+	sort.SliceStable(pm.RouteRules, func(i, j int) bool {
+		a, b := pm.RouteRules[i].path(), pm.RouteRules[j].path()
+		aPrefix, bPrefix := strings.HasSuffix(a, "*"), strings.HasSuffix(b, "*")
+		if aPrefix != bPrefix {
+			return !aPrefix
+		}
+		return len(a) > len(b)
+	})
+	for i := range pm.RouteRules {
+		pm.RouteRules[i].Priority = i + 1
+	}
+}
+
+// path returns the route rule's match in path rule notation ("/api/*").
+func (r routeRule) path() string {
+	if len(r.MatchRules) == 0 {
+		return ""
+	}
+	mr := r.MatchRules[0]
+	if mr.FullPathMatch != "" {
+		return mr.FullPathMatch
+	}
+	if mr.PrefixMatch != "" {
+		return mr.PrefixMatch + "*"
+	}
+	return ""
+}
+
+func pathToMatchRule(path string) matchRule {
+	if strings.HasSuffix(path, "*") {
+		return matchRule{PrefixMatch: strings.TrimSuffix(path, "*")}
+	}
+	return matchRule{FullPathMatch: path}
+}
+
+// weightedBackendServices resolves the services of backends to their backend
+// services in the spec.
+func (cfg LoadBalancerConfig) weightedBackendServices(projectID string,
+	backends []WeightedBackend) []weightedBackendService {
+	var result []weightedBackendService
+	for _, b := range backends {
+		result = append(result, weightedBackendService{
+			BackendService: backendServiceURL(projectID, cfg.backendFor(b.Service)),
+			Weight:         b.Weight,
+		})
+	}
+	return result
+}
+
+func (cfg LoadBalancerConfig) formatWeights(backends []WeightedBackend) string {
+	var parts []string
+	for _, b := range backends {
+		parts = append(parts, fmt.Sprintf("%s %d%%", cfg.backendFor(b.Service), b.Weight))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// services returns the names of all backend services referenced by the map.
+func (m *urlMap) services() []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(url string) {
+		if url == "" {
+			return
+		}
+		name := resourceName(url)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	add(m.DefaultService)
+	for _, pm := range m.PathMatchers {
+		add(pm.DefaultService)
+		for _, rule := range pm.PathRules {
+			add(rule.Service)
+		}
+		for _, rule := range pm.RouteRules {
+			add(rule.Service)
+			if rule.RouteAction != nil {
+				for _, wb := range rule.RouteAction.WeightedBackendServices {
+					add(wb.BackendService)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// isDefaultService reports whether service is the default service of the map
+// or of one of its path matchers.
+func (m *urlMap) isDefaultService(service string) bool {
+	if resourceName(m.DefaultService) == service {
+		return true
+	}
+	for _, pm := range m.PathMatchers {
+		if resourceName(pm.DefaultService) == service {
+			return true
+		}
+	}
+	return false
+}
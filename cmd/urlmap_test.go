@@ -0,0 +1,58 @@
+package cmd
+
+import "testing"
+
+func TestURLMapMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		m         urlMap
+		want      string
+		hostRules int
+	}{
+		{
+			name:      "new map",
+			m:         urlMap{},
+			want:      "path-matcher-1",
+			hostRules: 1,
+		},
+		{
+			name: "existing matcher for all hosts",
+			m: urlMap{
+				HostRules:    []hostRule{{Hosts: []string{"*"}, PathMatcher: "all"}},
+				PathMatchers: []pathMatcher{{Name: "all"}},
+			},
+			want:      "all",
+			hostRules: 1,
+		},
+		{
+			name: "default name taken by a host-specific matcher",
+			m: urlMap{
+				HostRules: []hostRule{
+					{Hosts: []string{"api.example.com"}, PathMatcher: "path-matcher-1"},
+					{Hosts: []string{"www.example.com"}, PathMatcher: "path-matcher-2"},
+				},
+				PathMatchers: []pathMatcher{{Name: "path-matcher-1"}, {Name: "path-matcher-2"}},
+			},
+			want:      "path-matcher-3",
+			hostRules: 3,
+		},
+		{
+			name: "host rule without its matcher",
+			m: urlMap{
+				HostRules: []hostRule{{Hosts: []string{"*"}, PathMatcher: "missing"}},
+			},
+			want:      "missing",
+			hostRules: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.matcher().Name; got != tt.want {
+				t.Errorf("matcher() = %s, want %s", got, tt.want)
+			}
+			if len(tt.m.HostRules) != tt.hostRules {
+				t.Errorf("%d host rules, want %d", len(tt.m.HostRules), tt.hostRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,559 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// wifIssuer describes the pool, provider and token claims of a CI platform.
+type wifIssuer struct {
+	Pool                string
+	Provider            string
+	PoolDisplayName     string
+	ProviderDisplayName string
+	IssuerURI           string
+	OwnerClaim          string
+	RepoClaim           string
+	ActorClaim          string
+	WorkflowClaim       string
+	RefPrefix           string
+	WorkflowDir         string
+}
+
+// githubIssuer returns the issuer of github.com, a GHE.com subdomain or a
+// GitHub Enterprise Server instance.
+func githubIssuer(host string) wifIssuer {
+	issuer := "https://token.actions.githubusercontent.com"
+	switch {
+	case host == "" || host == "github.com":
+	case strings.HasSuffix(host, ".ghe.com"):
+		issuer = "https://token.actions." + host
+	default:
+		issuer = "https://" + host + "/_services/token"
+	}
+	return wifIssuer{
+		Pool:                "github-pool",
+		Provider:            "github-provider",
+		PoolDisplayName:     "GitHub Actions Pool",
+		ProviderDisplayName: "GitHub Provider",
+		IssuerURI:           issuer,
+		OwnerClaim:          "repository_owner",
+		RepoClaim:           "repository",
+		ActorClaim:          "actor",
+		WorkflowClaim:       "workflow_ref",
+		RefPrefix:           "refs/heads/",
+		WorkflowDir:         ".github/workflows/",
+	}
+}
+
+// gitlabIssuer returns the issuer of a GitLab instance. GitLab's ref claim
+// holds the bare branch or tag name and ci_config_ref_uri the pipeline file.
+func gitlabIssuer(url string) wifIssuer {
+	return wifIssuer{
+		Pool:                "gitlab-pool",
+		Provider:            "gitlab-provider",
+		PoolDisplayName:     "GitLab CI Pool",
+		ProviderDisplayName: "GitLab Provider",
+		IssuerURI:           strings.TrimSuffix(url, "/"),
+		OwnerClaim:          "namespace_path",
+		RepoClaim:           "project_path",
+		ActorClaim:          "user_login",
+		WorkflowClaim:       "ci_config_ref_uri",
+	}
+}
+
+// providerName returns the full resource name of the provider.
+func (i wifIssuer) providerName(projectNumber string) string {
+	return fmt.Sprintf("projects/%s/locations/global/workloadIdentityPools/%s/providers/%s",
+		projectNumber, i.Pool, i.Provider)
+}
+
+// WIFPolicy restricts which CI tokens may impersonate the service account.
+// Empty lists do not restrict; the repository owner always does.
+type WIFPolicy struct {
+	Issuer       wifIssuer
+	Owner        string
+	Repositories []string
+	Refs         []string
+	Environments []string
+	Workflows    []string
+	// EnvironmentRefs restricts the refs of jobs running in one environment,
+	// e.g. production to main, on top of Refs.
+	EnvironmentRefs []wifEnvironmentRefs
+
+	// Explicit is set when a GCP_WIF_* key is configured. Only an explicit
+	// policy replaces the condition of an existing provider, which other
+	// repositories may share.
+	Explicit bool
+}
+
+// wifEnvironmentRefs holds the refs allowed to deploy to one environment.
+type wifEnvironmentRefs struct {
+	Environment string
+	Refs        []string
+}
+
+// loadWIFPolicy reads the GCP_WIF_* and GCP_<ENV>_WIF_REFS keys. Repositories
+// default to the configured repository.
+func loadWIFPolicy(issuer wifIssuer, owner, repo string) WIFPolicy {
+	p := WIFPolicy{
+		Issuer:       issuer,
+		Owner:        owner,
+		Repositories: splitList(viper.GetString("GCP_WIF_REPOSITORIES")),
+		Refs:         splitList(viper.GetString("GCP_WIF_REFS")),
+		Environments: splitList(viper.GetString("GCP_WIF_ENVIRONMENTS")),
+		Workflows:    splitList(viper.GetString("GCP_WIF_WORKFLOWS")),
+	}
+	for _, env := range deployEnvironments {
+		if refs := splitList(viper.GetString(environmentKey(env, "WIF_REFS"))); len(refs) > 0 {
+			p.EnvironmentRefs = append(p.EnvironmentRefs, wifEnvironmentRefs{Environment: env, Refs: refs})
+		}
+	}
+	p.Explicit = len(p.Repositories)+len(p.Refs)+len(p.Environments)+len(p.Workflows)+len(p.EnvironmentRefs) > 0
+	if len(p.Repositories) == 0 && repo != "" {
+		p.Repositories = []string{repo}
+	}
+	return p
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// allRepositories reports whether every repository of the owner is allowed.
+func (p WIFPolicy) allRepositories() bool {
+	return len(p.Repositories) == 0 || slices.Contains(p.Repositories, "*")
+}
+
+// repositories returns the allowed repositories as owner/repo.
+func (p WIFPolicy) repositories() []string {
+	var out []string
+	for _, r := range p.Repositories {
+		if r == "*" {
+			continue
+		}
+		if !strings.Contains(r, "/") {
+			r = p.Owner + "/" + r
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// owners returns the configured owner followed by the owners of allowed
+// repositories in other organizations.
+func (p WIFPolicy) owners() []string {
+	owners := []string{p.Owner}
+	for _, r := range p.repositories() {
+		owner := r[:strings.LastIndex(r, "/")]
+		if !slices.Contains(owners, owner) {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// tagLikeRef matches bare refs such as v*, v1 or v1.2.3 that name tags
+// rather than branches.
+var tagLikeRef = regexp.MustCompile(`^v[0-9*]`)
+
+// validate rejects bare refs that look like tags for issuers whose bare refs
+// are branches, as refs/heads/v* would never match a tag push.
+func (p WIFPolicy) validate() error {
+	if p.Issuer.RefPrefix == "" {
+		return nil
+	}
+	if err := validateRefs("GCP_WIF_REFS", p.Refs); err != nil {
+		return err
+	}
+	for _, e := range p.EnvironmentRefs {
+		if err := validateRefs(environmentKey(e.Environment, "WIF_REFS"), e.Refs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRefs(key string, refs []string) error {
+	for _, r := range refs {
+		if !strings.HasPrefix(r, "refs/") && tagLikeRef.MatchString(r) {
+			return fmt.Errorf("%s entry %q looks like a tag; write refs/tags/%s, "+
+				"or refs/heads/%s for a branch", key, r, r, r)
+		}
+	}
+	return nil
+}
+
+// refs returns the allowed refs with bare branch names expanded to the
+// issuer's ref prefix.
+func (p WIFPolicy) refs() []string {
+	return p.expandRefs(p.Refs)
+}
+
+// expandRefs expands bare branch names to the issuer's ref prefix. Issuers
+// without a prefix, like GitLab, put the bare branch or tag name in the ref
+// claim, so full refs are shortened instead.
+func (p WIFPolicy) expandRefs(refs []string) []string {
+	var out []string
+	for _, r := range refs {
+		switch {
+		case p.Issuer.RefPrefix == "":
+			r = strings.TrimPrefix(strings.TrimPrefix(r, "refs/heads/"), "refs/tags/")
+		case !strings.HasPrefix(r, "refs/"):
+			r = p.Issuer.RefPrefix + r
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// workflows returns the allowed workflow files as repository paths.
+func (p WIFPolicy) workflows() []string {
+	var out []string
+	for _, w := range p.Workflows {
+		if !strings.Contains(w, "/") {
+			w = p.Issuer.WorkflowDir + w
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// attributeMapping maps the claims the condition and principal sets refer to.
+// Optional claims are only mapped when used, as a missing claim fails the
+// token exchange.
+func (p WIFPolicy) attributeMapping() string {
+	i := p.Issuer
+	mapping := []string{
+		"google.subject=assertion.sub",
+		fmt.Sprintf("attribute.%s=assertion.%s", i.ActorClaim, i.ActorClaim),
+		fmt.Sprintf("attribute.%s=assertion.%s", i.RepoClaim, i.RepoClaim),
+		fmt.Sprintf("attribute.%s=assertion.%s", i.OwnerClaim, i.OwnerClaim),
+	}
+	if len(p.Refs) > 0 || len(p.EnvironmentRefs) > 0 {
+		mapping = append(mapping, "attribute.ref=assertion.ref")
+	}
+	if len(p.Environments) > 0 {
+		mapping = append(mapping, "attribute.environment=assertion.environment")
+	}
+	if len(p.Workflows) > 0 {
+		mapping = append(mapping, fmt.Sprintf("attribute.%s=assertion.%s", i.WorkflowClaim, i.WorkflowClaim))
+	}
+	return strings.Join(mapping, ",")
+}
+
+// attributeCondition builds the CEL condition of the provider. Environment
+// refs only apply to tokens of jobs in that environment; tokens without an
+// environment claim are left to the other clauses.
+func (p WIFPolicy) attributeCondition() string {
+	i := p.Issuer
+	var conds []string
+	if owners := p.owners(); len(owners) == 1 {
+		conds = append(conds, fmt.Sprintf("assertion.%s == %s", i.OwnerClaim, celString(p.Owner)))
+	} else {
+		conds = append(conds, fmt.Sprintf("assertion.%s in %s", i.OwnerClaim, celList(owners)))
+	}
+	if !p.allRepositories() {
+		conds = append(conds, fmt.Sprintf("assertion.%s in %s", i.RepoClaim, celList(p.repositories())))
+	}
+
+	if refs := p.refs(); len(refs) > 0 {
+		conds = append(conds, refCondition(refs))
+	}
+
+	if len(p.Environments) > 0 {
+		conds = append(conds, fmt.Sprintf("assertion.environment in %s", celList(p.Environments)))
+	}
+
+	for _, e := range p.EnvironmentRefs {
+		conds = append(conds, fmt.Sprintf("(!has(assertion.environment) || assertion.environment != %s || %s)",
+			celString(e.Environment), refCondition(p.expandRefs(e.Refs))))
+	}
+
+	if workflows := p.workflows(); len(workflows) > 0 {
+		var alts []string
+		for _, w := range workflows {
+			alts = append(alts, fmt.Sprintf("assertion.%s.contains(%s)", i.WorkflowClaim, celString("/"+w+"@")))
+		}
+		conds = append(conds, celOr(alts))
+	}
+	return strings.Join(conds, " && ")
+}
+
+// refCondition matches the ref claim against exact refs and prefixes ending
+// in *.
+func refCondition(refs []string) string {
+	var exact, alts []string
+	for _, r := range refs {
+		if prefix, ok := strings.CutSuffix(r, "*"); ok {
+			alts = append(alts, fmt.Sprintf("assertion.ref.startsWith(%s)", celString(prefix)))
+		} else {
+			exact = append(exact, r)
+		}
+	}
+	if len(exact) > 0 {
+		alts = append([]string{fmt.Sprintf("assertion.ref in %s", celList(exact))}, alts...)
+	}
+	return celOr(alts)
+}
+
+// wifTarget identifies the project and service account GitHub Actions
+// authenticate as.
+type wifTarget struct {
+	ProjectID           string
+	ProjectNumber       string
+	ServiceAccountEmail string
+	Policy              WIFPolicy
+}
+
+// ensureWorkloadIdentity creates or restores the issuer's pool and provider,
+// applies an explicit policy to an existing provider (a new one always gets
+// the policy's condition) and grants the policy's principal sets
+// roles/iam.workloadIdentityUser on the service account. Both project create
+// and service setup go through it.
+func ensureWorkloadIdentity(dry bool, t wifTarget) error {
+	if err := t.Policy.validate(); err != nil {
+		return err
+	}
+	i := t.Policy.Issuer
+	fmt.Println("  Checking Workload Identity Pool status...")
+	poolState := getPoolState(dry, t.ProjectID, i.Pool)
+
+	switch poolState {
+	case "DELETED":
+		fmt.Println("  Pool is soft-deleted, restoring...")
+		if err := runGcloud(dry, "iam", "workload-identity-pools", "undelete", i.Pool,
+			"--project="+t.ProjectID,
+			"--location=global",
+		); err != nil {
+			return fmt.Errorf("failed to restore workload identity pool: %w", err)
+		}
+		fmt.Println("  Pool restored successfully")
+	case "ACTIVE":
+		fmt.Println("  Pool already exists and is active")
+	case "NOT_FOUND":
+		fmt.Println("  Creating new pool...")
+		if err := runGcloud(dry, "iam", "workload-identity-pools", "create", i.Pool,
+			"--project="+t.ProjectID,
+			"--location=global",
+			"--display-name="+i.PoolDisplayName,
+		); err != nil {
+			return fmt.Errorf("failed to create workload identity pool: %w", err)
+		}
+		fmt.Println("  Pool created successfully")
+	}
+
+	if !dry {
+		fmt.Println("  Waiting for pool to be ready...")
+		time.Sleep(5 * time.Second)
+	}
+
+	fmt.Println("  Checking OIDC Provider status...")
+	providerState := getProviderState(dry, t.ProjectID, i.Pool, i.Provider)
+
+	switch providerState {
+	case "DELETED":
+		fmt.Println("  Provider is soft-deleted, restoring...")
+		if err := runGcloud(dry, "iam", "workload-identity-pools", "providers", "undelete", i.Provider,
+			"--project="+t.ProjectID,
+			"--location=global",
+			"--workload-identity-pool="+i.Pool,
+		); err != nil {
+			return fmt.Errorf("failed to restore OIDC provider: %w", err)
+		}
+		fmt.Println("  Provider restored successfully")
+		fallthrough
+	case "ACTIVE":
+		if err := applyExistingProviderPolicy(dry, t.ProjectID, t.Policy); err != nil {
+			return err
+		}
+	case "NOT_FOUND":
+		fmt.Println("  Creating new provider...")
+		if err := runGcloud(dry, "iam", "workload-identity-pools", "providers", "create-oidc", i.Provider,
+			"--project="+t.ProjectID,
+			"--location=global",
+			"--workload-identity-pool="+i.Pool,
+			"--display-name="+i.ProviderDisplayName,
+			"--issuer-uri="+i.IssuerURI,
+			"--attribute-mapping="+t.Policy.attributeMapping(),
+			"--attribute-condition="+t.Policy.attributeCondition(),
+		); err != nil {
+			return fmt.Errorf("failed to create OIDC provider: %w", err)
+		}
+		fmt.Println("  Provider created successfully")
+	}
+
+	fmt.Println("  Configuring repository access...")
+	for _, member := range t.Policy.members(t.ProjectNumber) {
+		if err := runGcloud(dry, "iam", "service-accounts", "add-iam-policy-binding", t.ServiceAccountEmail,
+			"--project="+t.ProjectID,
+			"--role=roles/iam.workloadIdentityUser",
+			"--member="+member,
+		); err != nil {
+			return fmt.Errorf("failed to grant %s: %w", member, err)
+		}
+		fmt.Printf("  ✓ %s\n", member[strings.LastIndex(member, "/attribute.")+1:])
+	}
+	return nil
+}
+
+// updateProviderPolicy replaces the issuer, attribute mapping and condition of the
+// existing provider.
+func updateProviderPolicy(dry bool, projectID string, p WIFPolicy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	i := p.Issuer
+	if err := runGcloud(dry, "iam", "workload-identity-pools", "providers", "update-oidc", i.Provider,
+		"--project="+projectID,
+		"--location=global",
+		"--workload-identity-pool="+i.Pool,
+		"--issuer-uri="+i.IssuerURI,
+		"--attribute-mapping="+p.attributeMapping(),
+		"--attribute-condition="+p.attributeCondition(),
+	); err != nil {
+		return fmt.Errorf("failed to update OIDC provider: %w", err)
+	}
+	return nil
+}
+
+func getPoolState(dry bool, projectID, poolID string) string {
+	if dry {
+		return "NOT_FOUND"
+	}
+	cmd := exec.Command("gcloud", "iam", "workload-identity-pools", "describe", poolID,
+		"--project="+projectID,
+		"--location=global",
+		"--format=value(state)",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return "NOT_FOUND"
+	}
+	state := strings.TrimSpace(string(output))
+	if state == "DELETED" {
+		return "DELETED"
+	}
+	return "ACTIVE"
+}
+
+func getProviderState(dry bool, projectID, poolID, providerID string) string {
+	if dry {
+		return "NOT_FOUND"
+	}
+	cmd := exec.Command("gcloud", "iam", "workload-identity-pools", "providers", "describe", providerID,
+		"--project="+projectID,
+		"--location=global",
+		"--workload-identity-pool="+poolID,
+		"--format=value(state)",
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return "NOT_FOUND"
+	}
+	state := strings.TrimSpace(string(output))
+	if state == "DELETED" {
+		return "DELETED"
+	}
+	return "ACTIVE"
+}
+
+// applyExistingProviderPolicy replaces the condition of an existing provider
+// when the policy is explicit. Otherwise the condition is kept, as it may
+// admit repositories set up from elsewhere, and repositories of the policy it
+// does not mention are reported.
+func applyExistingProviderPolicy(dry bool, projectID string, p WIFPolicy) error {
+	i := p.Issuer
+	current := ""
+	if !dry {
+		output, err := exec.Command("gcloud", "iam", "workload-identity-pools", "providers", "describe", i.Provider,
+			"--project="+projectID,
+			"--location=global",
+			"--workload-identity-pool="+i.Pool,
+			"--format=value(attributeCondition)",
+		).Output()
+		if err != nil {
+			return fmt.Errorf("failed to describe OIDC provider: %w", err)
+		}
+		current = strings.TrimSpace(string(output))
+	}
+	next := p.attributeCondition()
+
+	switch {
+	case current == next:
+		fmt.Println("  ✓ Provider condition is up to date")
+	case !p.Explicit:
+		fmt.Println("  Keeping the condition of the existing provider (set GCP_WIF_* to replace it):")
+		fmt.Printf("    %s\n", current)
+		if strings.Contains(current, "assertion."+i.RepoClaim+" in ") {
+			for _, r := range p.repositories() {
+				if !strings.Contains(current, celString(r)) {
+					fmt.Printf("  ⚠ The condition does not admit %s; add it to GCP_WIF_REPOSITORIES "+
+						"together with the repositories already allowed\n", r)
+				}
+			}
+		}
+	default:
+		fmt.Println("  Replacing the condition of the existing provider")
+		fmt.Printf("    old: %s\n", current)
+		fmt.Printf("    new: %s\n", next)
+		if err := updateProviderPolicy(dry, projectID, p); err != nil {
+			return err
+		}
+		fmt.Println("  Provider updated successfully")
+	}
+	return nil
+}
+
+// members returns the principal sets granted roles/iam.workloadIdentityUser.
+func (p WIFPolicy) members(projectNumber string) []string {
+	if p.allRepositories() {
+		return []string{p.principalSet(projectNumber, p.Issuer.OwnerClaim, p.Owner)}
+	}
+	var members []string
+	for _, r := range p.repositories() {
+		members = append(members, p.repoMember(projectNumber, r))
+	}
+	return members
+}
+
+// repoMember returns the principal set of a single repository.
+func (p WIFPolicy) repoMember(projectNumber, repo string) string {
+	return p.principalSet(projectNumber, p.Issuer.RepoClaim, repo)
+}
+
+func (p WIFPolicy) principalSet(projectNumber, attribute, value string) string {
+	return fmt.Sprintf("principalSet://iam.googleapis.com/projects/%s/locations/global/"+
+		"workloadIdentityPools/%s/attribute.%s/%s", projectNumber, p.Issuer.Pool, attribute, value)
+}
+
+func celString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func celList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = celString(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func celOr(conds []string) string {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return "(" + strings.Join(conds, " || ") + ")"
+}
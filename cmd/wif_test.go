@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestAttributeCondition(t *testing.T) {
+	github := githubIssuer("github.com")
+	gitlab := gitlabIssuer("https://gitlab.com")
+
+	tests := []struct {
+		name   string
+		policy WIFPolicy
+		want   string
+	}{
+		{
+			name:   "github repository",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"shop"}},
+			want:   "assertion.repository_owner == 'acme' && assertion.repository in ['acme/shop']",
+		},
+		{
+			name:   "github all repositories",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"*"}},
+			want:   "assertion.repository_owner == 'acme'",
+		},
+		{
+			name:   "github no repositories",
+			policy: WIFPolicy{Issuer: github, Owner: "acme"},
+			want:   "assertion.repository_owner == 'acme'",
+		},
+		{
+			name:   "github repository of another owner",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"shop", "partner/lib"}},
+			want: "assertion.repository_owner in ['acme', 'partner'] && " +
+				"assertion.repository in ['acme/shop', 'partner/lib']",
+		},
+		{
+			name: "github refs",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"shop"},
+				Refs: []string{"main", "release/*", "refs/tags/v*"}},
+			want: "assertion.repository_owner == 'acme' && assertion.repository in ['acme/shop'] && " +
+				"(assertion.ref in ['refs/heads/main'] || assertion.ref.startsWith('refs/heads/release/') || " +
+				"assertion.ref.startsWith('refs/tags/v'))",
+		},
+		{
+			name: "github single prefix ref",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"*"},
+				Refs: []string{"refs/tags/v*"}},
+			want: "assertion.repository_owner == 'acme' && assertion.ref.startsWith('refs/tags/v')",
+		},
+		{
+			name: "github environments and workflows",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"shop"},
+				Environments: []string{"staging", "production"},
+				Workflows:    []string{"gcloud-deploy.yml", ".github/workflows/release.yml"}},
+			want: "assertion.repository_owner == 'acme' && assertion.repository in ['acme/shop'] && " +
+				"assertion.environment in ['staging', 'production'] && " +
+				"(assertion.workflow_ref.contains('/.github/workflows/gcloud-deploy.yml@') || " +
+				"assertion.workflow_ref.contains('/.github/workflows/release.yml@'))",
+		},
+		{
+			name: "github quoting",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"*"},
+				Environments: []string{`it's`}},
+			want: `assertion.repository_owner == 'acme' && assertion.environment in ['it\'s']`,
+		},
+		{
+			name: "github environment refs",
+			policy: WIFPolicy{Issuer: github, Owner: "acme", Repositories: []string{"shop"},
+				Refs: []string{"main", "refs/tags/v*"},
+				EnvironmentRefs: []wifEnvironmentRefs{
+					{Environment: "staging", Refs: []string{"main", "release/*"}},
+					{Environment: "production", Refs: []string{"refs/tags/v*"}},
+				}},
+			want: "assertion.repository_owner == 'acme' && assertion.repository in ['acme/shop'] && " +
+				"(assertion.ref in ['refs/heads/main'] || assertion.ref.startsWith('refs/tags/v')) && " +
+				"(!has(assertion.environment) || assertion.environment != 'staging' || " +
+				"(assertion.ref in ['refs/heads/main'] || assertion.ref.startsWith('refs/heads/release/'))) && " +
+				"(!has(assertion.environment) || assertion.environment != 'production' || " +
+				"assertion.ref.startsWith('refs/tags/v'))",
+		},
+		{
+			name: "gitlab environment refs are bare names",
+			policy: WIFPolicy{Issuer: gitlab, Owner: "acme", Repositories: []string{"*"},
+				EnvironmentRefs: []wifEnvironmentRefs{{Environment: "production", Refs: []string{"refs/heads/main"}}}},
+			want: "assertion.namespace_path == 'acme' && " +
+				"(!has(assertion.environment) || assertion.environment != 'production' || " +
+				"assertion.ref in ['main'])",
+		},
+		{
+			name:   "gitlab project",
+			policy: WIFPolicy{Issuer: gitlab, Owner: "acme/web", Repositories: []string{"acme/web/shop"}},
+			want:   "assertion.namespace_path == 'acme/web' && assertion.project_path in ['acme/web/shop']",
+		},
+		{
+			name:   "gitlab project of another group",
+			policy: WIFPolicy{Issuer: gitlab, Owner: "acme/web", Repositories: []string{"shop", "tools/lib"}},
+			want: "assertion.namespace_path in ['acme/web', 'tools'] && " +
+				"assertion.project_path in ['acme/web/shop', 'tools/lib']",
+		},
+		{
+			name: "gitlab refs are bare names",
+			policy: WIFPolicy{Issuer: gitlab, Owner: "acme", Repositories: []string{"*"},
+				Refs: []string{"main", "v*", "refs/heads/develop", "refs/tags/release-*"}},
+			want: "assertion.namespace_path == 'acme' && (assertion.ref in ['main', 'develop'] || " +
+				"assertion.ref.startsWith('v') || assertion.ref.startsWith('release-'))",
+		},
+		{
+			name: "gitlab environments and pipeline files",
+			policy: WIFPolicy{Issuer: gitlab, Owner: "acme", Repositories: []string{"acme/shop"},
+				Environments: []string{"production"}, Workflows: []string{".gitlab-ci.yml"}},
+			want: "assertion.namespace_path == 'acme' && assertion.project_path in ['acme/shop'] && " +
+				"assertion.environment in ['production'] && " +
+				"assertion.ci_config_ref_uri.contains('/.gitlab-ci.yml@')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.attributeCondition(); got != tt.want {
+				t.Errorf("got\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttributeMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy WIFPolicy
+		want   string
+	}{
+		{
+			name:   "github",
+			policy: WIFPolicy{Issuer: githubIssuer("github.com"), Owner: "acme"},
+			want: "google.subject=assertion.sub,attribute.actor=assertion.actor," +
+				"attribute.repository=assertion.repository,attribute.repository_owner=assertion.repository_owner",
+		},
+		{
+			name: "gitlab with all claims",
+			policy: WIFPolicy{Issuer: gitlabIssuer("https://gitlab.com"), Owner: "acme",
+				Refs: []string{"main"}, Environments: []string{"production"}, Workflows: []string{".gitlab-ci.yml"}},
+			want: "google.subject=assertion.sub,attribute.user_login=assertion.user_login," +
+				"attribute.project_path=assertion.project_path,attribute.namespace_path=assertion.namespace_path," +
+				"attribute.ref=assertion.ref,attribute.environment=assertion.environment," +
+				"attribute.ci_config_ref_uri=assertion.ci_config_ref_uri",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.attributeMapping(); got != tt.want {
+				t.Errorf("got\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMembers(t *testing.T) {
+	const prefix = "principalSet://iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/"
+	tests := []struct {
+		name   string
+		policy WIFPolicy
+		want   []string
+	}{
+		{
+			name: "github repositories",
+			policy: WIFPolicy{Issuer: githubIssuer("github.com"), Owner: "acme",
+				Repositories: []string{"shop", "partner/lib"}},
+			want: []string{
+				prefix + "github-pool/attribute.repository/acme/shop",
+				prefix + "github-pool/attribute.repository/partner/lib",
+			},
+		},
+		{
+			name:   "github all repositories",
+			policy: WIFPolicy{Issuer: githubIssuer("github.com"), Owner: "acme", Repositories: []string{"shop", "*"}},
+			want:   []string{prefix + "github-pool/attribute.repository_owner/acme"},
+		},
+		{
+			name: "github refs do not change the members",
+			policy: WIFPolicy{Issuer: githubIssuer("github.com"), Owner: "acme", Repositories: []string{"shop"},
+				Refs: []string{"main"}, Environments: []string{"production"}, Workflows: []string{"gcloud-deploy.yml"}},
+			want: []string{prefix + "github-pool/attribute.repository/acme/shop"},
+		},
+		{
+			name: "gitlab project",
+			policy: WIFPolicy{Issuer: gitlabIssuer("https://gitlab.com"), Owner: "acme/web",
+				Repositories: []string{"shop"}},
+			want: []string{prefix + "gitlab-pool/attribute.project_path/acme/web/shop"},
+		},
+		{
+			name:   "gitlab all projects",
+			policy: WIFPolicy{Issuer: gitlabIssuer("https://gitlab.com"), Owner: "acme/web"},
+			want:   []string{prefix + "gitlab-pool/attribute.namespace_path/acme/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.members("123"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWIFPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  wifIssuer
+		refs    []string
+		wantErr bool
+	}{
+		{"github branches", githubIssuer("github.com"), []string{"main", "release/*", "vendor-update"}, false},
+		{"github full tag ref", githubIssuer("github.com"), []string{"refs/tags/v*", "refs/heads/v2"}, false},
+		{"github bare tag pattern", githubIssuer("github.com"), []string{"main", "v*"}, true},
+		{"github bare version", githubIssuer("github.com"), []string{"v1.2.3"}, true},
+		{"gitlab bare tag pattern", gitlabIssuer("https://gitlab.com"), []string{"main", "v*"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WIFPolicy{Issuer: tt.issuer, Owner: "acme", Refs: tt.refs}.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+
+			policy := WIFPolicy{Issuer: tt.issuer, Owner: "acme",
+				EnvironmentRefs: []wifEnvironmentRefs{{Environment: "production", Refs: tt.refs}}}
+			if err := policy.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() of environment refs = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadWIFPolicyEnvironmentRefs(t *testing.T) {
+	viper.Set("GCP_PRODUCTION_WIF_REFS", "main, refs/tags/v*")
+	defer viper.Set("GCP_PRODUCTION_WIF_REFS", "")
+
+	p := loadWIFPolicy(githubIssuer("github.com"), "acme", "shop")
+	want := []wifEnvironmentRefs{{Environment: "production", Refs: []string{"main", "refs/tags/v*"}}}
+	if !reflect.DeepEqual(p.EnvironmentRefs, want) {
+		t.Errorf("EnvironmentRefs = %v, want %v", p.EnvironmentRefs, want)
+	}
+	if !p.Explicit {
+		t.Error("a policy with environment refs is not explicit")
+	}
+}
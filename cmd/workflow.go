@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gcsetup/embedded"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Manage the generated CI workflow",
+}
+
+func init() {
+	rootCmd.AddCommand(workflowCmd)
+}
+
+// workflowData is what the deploy workflow and pipeline templates are rendered
+// with. The templates use {% %} delimiters, as ${{ }} is GitHub Actions syntax.
+type workflowData struct {
+	// Service, ProjectID and Region are fallbacks for the CI variables that
+	// gcsetup service sets.
+	Service   string
+	ProjectID string
+	Region    string
+
+	// Language is the language template the blocks come from.
+	Language string
+
+	Branch     string
+	TagPattern string
+
+	// Promotion is "direct", deploying Branch to production, or "staging",
+	// deploying Branch to staging and only tags to production.
+	Promotion        string
+	StagingService   string
+	StagingProjectID string
+
+	Previews              bool
+	PreviewEnvironment    string
+	StagingEnvironment    string
+	ProductionEnvironment string
+
+	TestCommand  string
+	Build        string
+	DeployFlags  string
+	PreviewFlags string
+
+	// SmokePath, if set, is requested from a new staging or production
+	// revision before it receives traffic, expecting SmokeStatus.
+	SmokePath   string
+	SmokeStatus string
+
+	// RolloutSteps, if set, are the space-separated traffic percentages a new
+	// staging or production revision is moved through, ending with 100.
+	RolloutSteps           string
+	RolloutInterval        string
+	RolloutIntervalSeconds int
+	RolloutErrorThreshold  string
+	RolloutMinRequests     int
+
+	// ReleaseRegistry, if set, is the repository path released images are
+	// copied to, e.g. europe-docker.pkg.dev/acme-prod/releases.
+	ReleaseRegistry string
+}
+
+// loadWorkflowData reads the GCP_WORKFLOW_* settings and the service
+// defaults from the config.
+func loadWorkflowData() (workflowData, error) {
+	get := func(key, fallback string) string {
+		if v := viper.GetString(key); v != "" {
+			return v
+		}
+		return fallback
+	}
+
+	d := workflowData{
+		Service:               viper.GetString("GCP_CLOUD_RUN_SERVICE"),
+		ProjectID:             viper.GetString("GCP_PROJECT_ID"),
+		Region:                viper.GetString("GCP_REGION"),
+		Branch:                get("GCP_WORKFLOW_BRANCH", "main"),
+		TagPattern:            get("GCP_WORKFLOW_TAGS", "v*.*.*"),
+		Promotion:             get("GCP_WORKFLOW_PROMOTION", "direct"),
+		StagingService:        stagingService(viper.GetString("GCP_CLOUD_RUN_SERVICE")),
+		StagingProjectID:      get(environmentKey("staging", "PROJECT_ID"), viper.GetString("GCP_PROJECT_ID")),
+		Previews:              get("GCP_WORKFLOW_PREVIEWS", "true") == "true",
+		PreviewEnvironment:    "preview",
+		StagingEnvironment:    "staging",
+		ProductionEnvironment: "production",
+		TestCommand:           strings.TrimSpace(viper.GetString("GCP_WORKFLOW_TEST_COMMAND")),
+		Build:                 get("GCP_WORKFLOW_BUILD", "cloudbuild"),
+		DeployFlags:           viper.GetString("GCP_WORKFLOW_DEPLOY_FLAGS"),
+		PreviewFlags:          get("GCP_WORKFLOW_PREVIEW_FLAGS", "--allow-unauthenticated"),
+		SmokePath:             viper.GetString("GCP_WORKFLOW_SMOKE_PATH"),
+		SmokeStatus:           get("GCP_WORKFLOW_SMOKE_STATUS", "200"),
+		ReleaseRegistry:       strings.TrimSuffix(viper.GetString("GCP_WORKFLOW_RELEASE_REGISTRY"), "/"),
+		RolloutInterval:       get("GCP_WORKFLOW_ROLLOUT_INTERVAL", "5m"),
+		RolloutErrorThreshold: get("GCP_WORKFLOW_ROLLOUT_ERROR_THRESHOLD", "1"),
+		RolloutMinRequests:    rolloutMinRequests,
+	}
+	if d.Build != "cloudbuild" && d.Build != "docker" {
+		return d, fmt.Errorf("GCP_WORKFLOW_BUILD must be cloudbuild or docker, got %q", d.Build)
+	}
+	if d.SmokePath != "" && !strings.HasPrefix(d.SmokePath, "/") {
+		return d, fmt.Errorf("GCP_WORKFLOW_SMOKE_PATH must start with /, got %q", d.SmokePath)
+	}
+	if status, err := strconv.Atoi(d.SmokeStatus); err != nil || status < 100 || status > 599 {
+		return d, fmt.Errorf("GCP_WORKFLOW_SMOKE_STATUS must be an HTTP status code, got %q", d.SmokeStatus)
+	}
+	if steps := viper.GetString("GCP_WORKFLOW_ROLLOUT_STEPS"); steps != "" {
+		parsed, err := parseTrafficSteps(steps)
+		if err != nil || parsed[len(parsed)-1] != 100 {
+			return d, fmt.Errorf("GCP_WORKFLOW_ROLLOUT_STEPS must be increasing percentages ending with 100, "+
+				"got %q", steps)
+		}
+		d.RolloutSteps = strings.Trim(fmt.Sprint(parsed), "[]")
+	}
+	interval, err := time.ParseDuration(d.RolloutInterval)
+	if err != nil || interval < time.Second {
+		return d, fmt.Errorf("GCP_WORKFLOW_ROLLOUT_INTERVAL must be a duration such as 5m, got %q", d.RolloutInterval)
+	}
+	d.RolloutIntervalSeconds = int(interval.Seconds())
+	if threshold, err := strconv.ParseFloat(d.RolloutErrorThreshold, 64); err != nil || threshold < 0 {
+		return d, fmt.Errorf("GCP_WORKFLOW_ROLLOUT_ERROR_THRESHOLD must be a number of percentage points, got %q",
+			d.RolloutErrorThreshold)
+	}
+	if d.Promotion != "direct" && d.Promotion != "staging" {
+		return d, fmt.Errorf("GCP_WORKFLOW_PROMOTION must be direct or staging, got %q", d.Promotion)
+	}
+	return d, nil
+}
+
+// CandidateDeploy reports whether new staging and production revisions are
+// deployed without traffic and verified before they serve.
+func (d workflowData) CandidateDeploy() bool {
+	return d.SmokePath != "" || d.RolloutSteps != ""
+}
+
+// promotesThroughStaging reports whether the workflow deploys the branch to
+// staging rather than production.
+func promotesThroughStaging() bool {
+	return viper.GetString("GCP_WORKFLOW_PROMOTION") == "staging"
+}
+
+// stagingService returns GCP_STAGING_CLOUD_RUN_SERVICE or the production
+// service with a -staging suffix, so the two never collide in one project.
+func stagingService(service string) string {
+	if s := viper.GetString(environmentKey("staging", "CLOUD_RUN_SERVICE")); s != "" {
+		return s
+	}
+	if service == "" {
+		return ""
+	}
+	return service + "-staging"
+}
+
+// templateFuncs returns the functions available to templates. include
+// executes another template of t to a string, so blocks can be tested for
+// emptiness and indented.
+func templateFuncs(t **template.Template) template.FuncMap {
+	return template.FuncMap{
+		// fallback renders ` || 'value'` for a GitHub expression, or nothing.
+		"fallback": func(value string) string {
+			if value == "" {
+				return ""
+			}
+			return " || '" + strings.ReplaceAll(value, "'", "''") + "'"
+		},
+		// indent prefixes every line of s with n spaces.
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			lines := strings.Split(s, "\n")
+			for i, line := range lines {
+				if line != "" {
+					lines[i] = pad + line
+				}
+			}
+			return strings.Join(lines, "\n")
+		},
+		"include": func(name string, data any) (string, error) {
+			var buf bytes.Buffer
+			if err := (*t).ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return strings.Trim(buf.String(), "\n"), nil
+		},
+		"gitlabRegex": gitlabRegex,
+		// lines joins the non-empty arguments with newlines.
+		"lines": func(parts ...string) string {
+			var nonEmpty []string
+			for _, p := range parts {
+				if p = strings.TrimSpace(p); p != "" {
+					nonEmpty = append(nonEmpty, p)
+				}
+			}
+			return strings.Join(nonEmpty, "\n")
+		},
+	}
+}
+
+// gitlabRegex converts a GitHub Actions filter pattern such as v*.*.* into
+// the equivalent GitLab CI regular expression. * matches within a path
+// segment, ** across segments; ?, + and [] have the same meaning in both.
+func gitlabRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteString("/^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString(`[^\/]*`)
+			}
+		case '?', '+', '[', ']':
+			b.WriteByte(c)
+		case '.', '/', '\\', '(', ')', '{', '}', '|', '^', '$':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString("$/")
+	return b.String()
+}
+
+// renderTemplate executes a workflow template. The overlays are parsed after
+// it and may redefine its blocks, e.g. a language template's test-command.
+func renderTemplate(name string, src []byte, data any, overlays ...[]byte) ([]byte, error) {
+	var tmpl *template.Template
+	tmpl = template.New(name).Delims("{%", "%}").Funcs(templateFuncs(&tmpl)).Option("missingkey=error")
+	if _, err := tmpl.Parse(string(src)); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	for i, overlay := range overlays {
+		if _, err := tmpl.New(fmt.Sprintf("%s-overlay-%d", name, i)).Parse(string(overlay)); err != nil {
+			return nil, fmt.Errorf("failed to parse template overlay for %s: %w", name, err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n'), nil
+}
+
+// ciFile returns the path of the generated CI file of a provider, relative to
+// the repository root, and its template.
+func ciFile(ci string) (string, []byte, error) {
+	switch ci {
+	case "github":
+		return filepath.Join(".github", "workflows", "gcloud-deploy.yml"), embedded.DeployWorkflow, nil
+	case "gitlab":
+		return ".gitlab-ci.yml", embedded.GitLabPipeline, nil
+	default:
+		return "", nil, fmt.Errorf("unknown CI provider %q, expected github or gitlab", ci)
+	}
+}
+
+// renderedCIFile is a rendered CI file with its template version, a digest of
+// all template sources, and the override files used.
+type renderedCIFile struct {
+	Content   []byte
+	Version   string
+	Overrides []string
+}
+
+// renderCIFile renders the CI file of a provider with a language template and
+// the template overrides.
+func renderCIFile(ci string, lang languageTemplate, data workflowData) (renderedCIFile, error) {
+	path, builtin, err := ciFile(ci)
+	if err != nil {
+		return renderedCIFile{}, err
+	}
+	name := strings.TrimPrefix(filepath.Base(path), ".")
+	tmpl, err := resolveTemplate(name, builtin)
+	if err != nil {
+		return renderedCIFile{}, err
+	}
+	overlays := append([][]byte{lang.Source}, tmpl.Overlays...)
+
+	data.Language = lang.Name
+	content, err := renderTemplate(name, tmpl.Source, data, overlays...)
+	if err != nil {
+		return renderedCIFile{}, err
+	}
+	h := sha256.New()
+	h.Write(tmpl.Source)
+	for _, overlay := range overlays {
+		h.Write(overlay)
+	}
+	return renderedCIFile{
+		Content:   content,
+		Version:   hex.EncodeToString(h.Sum(nil))[:12],
+		Overrides: tmpl.Files,
+	}, nil
+}
+
+// recordWorkflow stores the rendered CI file as the base of later upgrades.
+func recordWorkflow(path, ci, lang, version string, content []byte) error {
+	base := filepath.Join(stateDir, "base", filepath.Base(path))
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(base, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", base, err)
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if st.Workflows == nil {
+		st.Workflows = map[string]*WorkflowState{}
+	}
+	st.Workflows[filepath.ToSlash(path)] = &WorkflowState{
+		CI:         ci,
+		Template:   lang,
+		Version:    version,
+		Base:       filepath.ToSlash(base),
+		RenderedAt: time.Now().Format(time.RFC3339),
+	}
+	return saveState(st)
+}
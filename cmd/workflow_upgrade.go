@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gcsetup/internal/diff3"
+
+	"github.com/spf13/cobra"
+)
+
+var workflowUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Merge template changes into the generated CI file",
+	Long: `Re-renders the CI file generated by gcsetup init with the current template
+and merges it into the file in the repository:
+
+  - changes made by the template since init are applied
+  - your edits to the file are kept
+  - where both changed the same lines, conflict markers are written
+
+The changes are shown as a diff before anything is written. The new rendering
+becomes the base of the next upgrade.`,
+	RunE: runWorkflowUpgrade,
+}
+
+var workflowDryRun bool
+var workflowNonInteractive bool
+var workflowTemplate string
+
+func init() {
+	workflowCmd.AddCommand(workflowUpgradeCmd)
+	workflowUpgradeCmd.Flags().BoolVar(&workflowDryRun, "dry-run", false, "Show the diff without writing")
+	workflowUpgradeCmd.Flags().BoolVarP(&workflowNonInteractive, "yes", "y", false,
+		"Write the merged file without asking")
+	workflowUpgradeCmd.Flags().StringVar(&workflowTemplate, "template", "",
+		"Switch to another language template (default: the one used at init)")
+}
+
+func runWorkflowUpgrade(cmd *cobra.Command, args []string) error {
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	if len(st.Workflows) == 0 {
+		path, _, err := ciFile(ciProviderName())
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s has no recorded template version; move it aside, run gcsetup init "+
+				"and reapply your changes to the new file", path)
+		}
+		return fmt.Errorf("no generated CI file recorded in %s, run gcsetup init first", statePath())
+	}
+
+	data, err := loadWorkflowData()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(st.Workflows))
+	for path := range st.Workflows {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := upgradeWorkflow(path, st.Workflows[path], data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upgradeWorkflow(path string, ws *WorkflowState, data workflowData) error {
+	name := ws.Template
+	if workflowTemplate != "" {
+		name = workflowTemplate
+	}
+	lang, err := findLanguageTemplate(name)
+	if err != nil {
+		return err
+	}
+	r, err := renderCIFile(ws.CI, lang, data)
+	if err != nil {
+		return err
+	}
+	rendered, version := r.Content, r.Version
+
+	current, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s was removed, run gcsetup init to generate it again", path)
+	}
+	if err != nil {
+		return err
+	}
+	base, err := os.ReadFile(ws.Base)
+	if err != nil {
+		return fmt.Errorf("failed to read the base of %s: %w", path, err)
+	}
+
+	fmt.Printf("%s: %s template %s → %s %s\n", path, ws.Template, ws.Version, lang.Name, version)
+	if string(rendered) == string(base) {
+		fmt.Println("✓ Already up to date")
+		return nil
+	}
+
+	merged, conflicts := diff3.Merge(diff3.Lines(string(base)), diff3.Lines(string(current)),
+		diff3.Lines(string(rendered)), "yours", "template "+version)
+	result := []byte(strings.Join(merged, "\n") + "\n")
+
+	if diff := diff3.Unified("a/"+path, "b/"+path, diff3.Lines(string(current)), merged, 3); diff != "" {
+		fmt.Println()
+		fmt.Print(diff)
+		fmt.Println()
+	} else {
+		fmt.Println("✓ The template changes are already in the file")
+	}
+	if conflicts > 0 {
+		fmt.Printf("⚠ %d conflict(s) between your edits and the template\n", conflicts)
+	}
+
+	if workflowDryRun {
+		fmt.Printf("[dry-run] Would write %s and record template %s\n", path, version)
+		return nil
+	}
+	if !workflowNonInteractive && !promptConfirm(fmt.Sprintf("Write %s?", path)) {
+		fmt.Println("Skipped", path)
+		return nil
+	}
+
+	if err := os.WriteFile(path, result, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := recordWorkflow(path, ws.CI, lang.Name, version, rendered); err != nil {
+		return fmt.Errorf("failed to record template %s: %w", version, err)
+	}
+	if conflicts > 0 {
+		fmt.Printf("⚠ Wrote %s with conflict markers; resolve the <<<<<<< sections before committing\n", path)
+	} else {
+		fmt.Println("✓ Upgraded", path)
+	}
+	return nil
+}
@@ -2,15 +2,28 @@
 package embedded
 
 import (
-	_ "embed"
+	"embed"
 )
 
-// DeployWorkflow contains the GitHub Actions CI/CD workflow template.
+// DeployWorkflow contains the GitHub Actions CI/CD workflow template. Like
+// GitLabPipeline it is a text/template with {% %} delimiters.
 //
 //go:embed gcloud-deploy.yml
 var DeployWorkflow []byte
 
+// GitLabPipeline contains the GitLab CI/CD pipeline template.
+//
+//go:embed gitlab-ci.yml
+var GitLabPipeline []byte
+
 // EnvTemplate contains the .env.gcloud configuration template.
 //
 //go:embed env.gcloud.template
 var EnvTemplate []byte
+
+// Templates contains the language templates, templates/<name>.yml. Each one
+// starts with a /* description */ comment and redefines blocks of
+// DeployWorkflow and GitLabPipeline such as test-command.
+//
+//go:embed templates/*.yml
+var Templates embed.FS
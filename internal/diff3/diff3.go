@@ -0,0 +1,218 @@
+// Package diff3 implements line-based diffs and three-way merges, enough to
+// upgrade generated files that users may have edited.
+package diff3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines splits s into lines without their newlines. A trailing newline does
+// not produce an empty last line.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// match returns, for every line of a, the index of the line of b it is paired
+// with in a longest common subsequence, or -1.
+func match(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+
+	// Common prefix and suffix keep the quadratic part small for the usual
+	// case of a few edits in a long file.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		m[pre] = pre
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		m[len(a)-1-suf] = len(b) - 1 - suf
+		suf++
+	}
+
+	a2, b2 := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, k := len(a2), len(b2)
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, k+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := k - 1; j >= 0; j-- {
+			switch {
+			case a2[i] == b2[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < k; {
+		switch {
+		case a2[i] == b2[j]:
+			m[pre+i] = pre + j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return m
+}
+
+// Op is a diff operation on one line.
+type Op struct {
+	Kind byte // ' ', '-' or '+'
+	Line string
+}
+
+// Diff returns the operations turning a into b.
+func Diff(a, b []string) []Op {
+	m := match(a, b)
+	var ops []Op
+	j := 0
+	for i, line := range a {
+		if m[i] < 0 {
+			ops = append(ops, Op{'-', line})
+			continue
+		}
+		for ; j < m[i]; j++ {
+			ops = append(ops, Op{'+', b[j]})
+		}
+		ops = append(ops, Op{' ', line})
+		j++
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, Op{'+', b[j]})
+	}
+	return ops
+}
+
+// Unified renders the diff of a and b in unified format with n lines of
+// context, or "" if they are equal.
+func Unified(aName, bName string, a, b []string, n int) string {
+	ops := Diff(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		for start < len(ops) && ops[start].Kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Extend the hunk while changes are at most 2n lines apart.
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].Kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*n {
+				break
+			}
+		}
+		from, to := max(start-n, 0), min(end+n, len(ops))
+
+		aLine, bLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.Kind != '+' {
+				aLine++
+			}
+			if op.Kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.Kind != '+' {
+				aCount++
+			}
+			if op.Kind != '-' {
+				bCount++
+			}
+		}
+
+		// An empty range is numbered after the line it follows, as in diff -u.
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", op.Kind, op.Line)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// Merge combines the changes from base to ours and from base to theirs.
+// Where both sides changed the same lines differently, the result contains
+// conflict markers labelled with oursName and theirsName, and conflicts
+// counts them.
+func Merge(base, ours, theirs []string, oursName, theirsName string) (merged []string, conflicts int) {
+	mo, mt := match(base, ours), match(base, theirs)
+
+	i, o, t := 0, 0, 0
+	for i < len(base) || o < len(ours) || t < len(theirs) {
+		if i < len(base) && mo[i] == o && mt[i] == t {
+			merged = append(merged, base[i])
+			i, o, t = i+1, o+1, t+1
+			continue
+		}
+
+		// The next base line both sides kept ends the unstable chunk.
+		j := i
+		for j < len(base) && (mo[j] < 0 || mt[j] < 0) {
+			j++
+		}
+		oEnd, tEnd := len(ours), len(theirs)
+		if j < len(base) {
+			oEnd, tEnd = mo[j], mt[j]
+		}
+		b, x, y := base[i:j], ours[o:oEnd], theirs[t:tEnd]
+
+		switch {
+		case equal(x, b):
+			merged = append(merged, y...)
+		case equal(y, b), equal(x, y):
+			merged = append(merged, x...)
+		default:
+			conflicts++
+			merged = append(merged, "<<<<<<< "+oursName)
+			merged = append(merged, x...)
+			merged = append(merged, "=======")
+			merged = append(merged, y...)
+			merged = append(merged, ">>>>>>> "+theirsName)
+		}
+		i, o, t = j, oEnd, tEnd
+	}
+	return merged, conflicts
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
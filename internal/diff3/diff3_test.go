@@ -0,0 +1,279 @@
+package diff3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\nb", []string{"a", "b"}},
+		{"a\nb\n", []string{"a", "b"}},
+		{"a\n\n", []string{"a", ""}},
+		{"\n", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := Lines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string // one op per line, kind followed by the line
+	}{
+		{"equal", "a\nb\n", "a\nb\n", " a\n b\n"},
+		{"insert", "a\nc\n", "a\nb\nc\n", " a\n+b\n c\n"},
+		{"delete", "a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", " a\n-b\n+x\n c\n"},
+		{"empty a", "", "a\nb\n", "+a\n+b\n"},
+		{"empty b", "a\nb\n", "", "-a\n-b\n"},
+		{"both empty", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			for _, op := range Diff(Lines(tt.a), Lines(tt.b)) {
+				got.WriteString(string(op.Kind) + op.Line + "\n")
+			}
+			if got.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	long := func(change map[int]string) []string {
+		var lines []string
+		for i := 1; i <= 20; i++ {
+			line := "line " + string(rune('a'+i-1))
+			if c, ok := change[i]; ok {
+				if c == "" {
+					continue
+				}
+				line = c
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name string
+		a, b []string
+		want string
+	}{
+		{"equal", long(nil), long(nil), ""},
+		{
+			"single change",
+			long(nil), long(map[int]string{10: "changed"}),
+			"--- a/f\n+++ b/f\n@@ -7,7 +7,7 @@\n" +
+				" line g\n line h\n line i\n-line j\n+changed\n line k\n line l\n line m\n",
+		},
+		{
+			"change at the start",
+			long(nil), long(map[int]string{1: "changed"}),
+			"--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-line a\n+changed\n line b\n line c\n line d\n",
+		},
+		{
+			"two hunks",
+			long(nil), long(map[int]string{2: "", 18: "changed"}),
+			"--- a/f\n+++ b/f\n" +
+				"@@ -1,5 +1,4 @@\n line a\n-line b\n line c\n line d\n line e\n" +
+				"@@ -15,6 +14,6 @@\n line o\n line p\n line q\n-line r\n+changed\n line s\n line t\n",
+		},
+		{
+			"nearby changes share a hunk",
+			long(nil), long(map[int]string{5: "x", 9: "y"}),
+			"--- a/f\n+++ b/f\n@@ -2,11 +2,11 @@\n line b\n line c\n line d\n-line e\n+x\n" +
+				" line f\n line g\n line h\n-line i\n+y\n line j\n line k\n line l\n",
+		},
+		{
+			"from empty",
+			nil, []string{"a", "b"},
+			"--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"to empty",
+			[]string{"a", "b"}, nil,
+			"--- a/f\n+++ b/f\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a/f", "b/f", tt.a, tt.b, 3); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		wantConflicts      int
+	}{
+		{
+			name:   "no changes",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   "a\nb\nC\n",
+		},
+		{
+			name:   "non-overlapping edits",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "non-overlapping insertions",
+			base:   "a\nb\nc\n",
+			ours:   "a\nours\nb\nc\n",
+			theirs: "a\nb\ntheirs\nc\n",
+			want:   "a\nours\nb\ntheirs\nc\n",
+		},
+		{
+			name:   "identical edits",
+			base:   "a\nb\nc\n",
+			ours:   "a\nX\nc\nd\n",
+			theirs: "a\nX\nc\nd\n",
+			want:   "a\nX\nc\nd\n",
+		},
+		{
+			name:          "same position inserted differently",
+			base:          "a\nc\n",
+			ours:          "a\nours\nc\n",
+			theirs:        "a\ntheirs\nc\n",
+			want:          "a\n<<<<<<< yours\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "same line edited differently",
+			base:          "a\nb\nc\n",
+			ours:          "a\nours\nc\n",
+			theirs:        "a\ntheirs\nc\n",
+			want:          "a\n<<<<<<< yours\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "deletion against an edit",
+			base:          "a\nb\nc\n",
+			ours:          "a\nc\n",
+			theirs:        "a\nB\nc\n",
+			want:          "a\n<<<<<<< yours\n=======\nB\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "deletion of an unchanged line",
+			base:   "a\nb\nc\n",
+			ours:   "a\nc\n",
+			theirs: "a\nb\nc\nd\n",
+			want:   "a\nc\nd\n",
+		},
+		{
+			name:   "two conflicts",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "a\nB1\nc\nD1\ne\n",
+			theirs: "a\nB2\nc\nD2\ne\n",
+			want: "a\n<<<<<<< yours\nB1\n=======\nB2\n>>>>>>> template\nc\n" +
+				"<<<<<<< yours\nD1\n=======\nD2\n>>>>>>> template\ne\n",
+			wantConflicts: 2,
+		},
+		{
+			name:   "empty base, identical sides",
+			base:   "",
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:          "empty base, different sides",
+			base:          "",
+			ours:          "a\n",
+			theirs:        "b\n",
+			want:          "<<<<<<< yours\na\n=======\nb\n>>>>>>> template\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "empty ours, unchanged theirs",
+			base:   "a\nb\n",
+			ours:   "",
+			theirs: "a\nb\n",
+			want:   "",
+		},
+		{
+			name:          "empty ours, changed theirs",
+			base:          "a\nb\n",
+			ours:          "",
+			theirs:        "a\nB\n",
+			want:          "<<<<<<< yours\n=======\na\nB\n>>>>>>> template\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "empty theirs, unchanged ours",
+			base:   "a\nb\n",
+			ours:   "a\nb\n",
+			theirs: "",
+			want:   "",
+		},
+		{
+			name:   "all empty",
+			base:   "",
+			ours:   "",
+			theirs: "",
+			want:   "",
+		},
+		{
+			name:   "no trailing newline",
+			base:   "a\nb\nc",
+			ours:   "a\nB\nc",
+			theirs: "a\nb\nc\nd",
+			want:   "a\nB\nc\nd\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := Merge(Lines(tt.base), Lines(tt.ours), Lines(tt.theirs), "yours", "template")
+			got := ""
+			if len(merged) > 0 {
+				// upgradeWorkflow writes the merge this way.
+				got = strings.Join(merged, "\n") + "\n"
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if conflicts != tt.wantConflicts {
+				t.Errorf("got %d conflicts, want %d", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
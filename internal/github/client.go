@@ -0,0 +1,508 @@
+// Package github is a small client for the GitHub REST endpoints gcsetup
+// uses: Actions secrets and variables, and deployment environments.
+package github
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Client calls the REST API of github.com, a GHE.com subdomain or a GitHub
+// Enterprise Server instance.
+type Client struct {
+	// BaseURL is the API root, e.g. https://api.github.com. Tests point it at
+	// an httptest server.
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the API of host.
+func NewClient(host, token string) *Client {
+	return &Client{BaseURL: APIURL(host), Token: token, HTTPClient: http.DefaultClient}
+}
+
+// APIURL returns the REST API root of a GitHub host.
+func APIURL(host string) string {
+	switch {
+	case host == "" || host == "github.com":
+		return "https://api.github.com"
+	case strings.HasSuffix(host, ".ghe.com"):
+		return "https://api." + host
+	default:
+		return "https://" + host + "/api/v3"
+	}
+}
+
+// Token returns a token for host from GH_TOKEN / GITHUB_TOKEN (or their
+// GH_ENTERPRISE_ variants for other hosts), falling back to gh's hosts.yml and
+// finally to `gh auth token`, which also covers tokens kept in the keyring.
+func Token(host string) (string, error) {
+	vars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "" && host != "github.com" && !strings.HasSuffix(host, ".ghe.com") {
+		vars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, v := range vars {
+		if t := os.Getenv(v); t != "" {
+			return t, nil
+		}
+	}
+
+	if t := hostsFileToken(host); t != "" {
+		return t, nil
+	}
+
+	if host == "" {
+		host = "github.com"
+	}
+	output, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+	if err == nil && strings.TrimSpace(string(output)) != "" {
+		return strings.TrimSpace(string(output)), nil
+	}
+	return "", fmt.Errorf("no GitHub token for %s: set GH_TOKEN or run gh auth login --hostname %s", host, host)
+}
+
+// hostsFileToken reads oauth_token of host from gh's hosts.yml. The file is a
+// flat two-level mapping, so a line scan suffices.
+func hostsFileToken(host string) string {
+	if host == "" {
+		host = "github.com"
+	}
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "gh")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gh")
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	inHost := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			inHost = strings.TrimSuffix(strings.TrimSpace(line), ":") == host
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if inHost && ok && key == "oauth_token" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// StatusError is returned for non-2xx responses.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) do(method, path string, in, out any) error {
+	_, err := c.send(method, path, in, out)
+	return err
+}
+
+// page GETs one page of a list into out and returns the URL of the next page
+// from the Link header, or "".
+func (c *Client) page(path string, out any) (string, error) {
+	header, err := c.send("GET", path, nil, out)
+	if err != nil {
+		return "", err
+	}
+	return nextLink(header.Get("Link")), nil
+}
+
+// nextLink returns the rel="next" URL of a Link header, e.g.
+// <https://api.github.com/...?page=2>; rel="next", <...>; rel="last".
+func nextLink(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// send performs a request. path is relative to BaseURL, or an absolute URL
+// such as a pagination link, which must have the scheme and host of BaseURL
+// so that the token is not sent elsewhere.
+func (c *Client) send(method, path string, in, out any) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	target := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		target = strings.TrimSuffix(c.BaseURL, "/") + path
+	} else if !c.sameOrigin(path) {
+		return nil, fmt.Errorf("refusing to send a request to %s, which is outside %s", path, c.BaseURL)
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(data)),
+		}
+	}
+	if out != nil && len(data) > 0 {
+		return resp.Header, json.Unmarshal(data, out)
+	}
+	return resp.Header, nil
+}
+
+// sameOrigin reports whether rawURL has the scheme and host of BaseURL.
+func (c *Client) sameOrigin(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, base.Scheme) && strings.EqualFold(u.Host, base.Host)
+}
+
+// Repo identifies a repository and optionally one of its environments.
+// Secrets and variables of a repo with Environment set are environment-scoped.
+type Repo struct {
+	Owner       string
+	Name        string
+	Environment string
+}
+
+func (r Repo) String() string {
+	if r.Environment != "" {
+		return r.Owner + "/" + r.Name + " (" + r.Environment + ")"
+	}
+	return r.Owner + "/" + r.Name
+}
+
+func (r Repo) path() string {
+	return "/repos/" + url.PathEscape(r.Owner) + "/" + url.PathEscape(r.Name)
+}
+
+// ActionsPath returns the prefix of the secrets and variables endpoints.
+func (r Repo) ActionsPath() string {
+	if r.Environment != "" {
+		return r.path() + "/environments/" + url.PathEscape(r.Environment)
+	}
+	return r.path() + "/actions"
+}
+
+// PublicKey is the key secrets are encrypted with.
+type PublicKey struct {
+	KeyID string `json:"key_id"`
+	Key   string `json:"key"`
+}
+
+// PublicKey fetches the repository's (or environment's) secrets public key.
+func (c *Client) PublicKey(r Repo) (*PublicKey, error) {
+	var key PublicKey
+	if err := c.do("GET", r.ActionsPath()+"/secrets/public-key", nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Secret is a secret's metadata; values cannot be read back.
+type Secret struct {
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ListSecrets returns all secrets, following pagination.
+func (c *Client) ListSecrets(r Repo) ([]Secret, error) {
+	var all []Secret
+	for path := r.ActionsPath() + "/secrets?per_page=100"; path != ""; {
+		var resp struct {
+			Secrets []Secret `json:"secrets"`
+		}
+		next, err := c.page(path, &resp)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Secrets...)
+		path = next
+	}
+	return all, nil
+}
+
+// SetSecret encrypts value with key and creates or updates the secret.
+func (c *Client) SetSecret(r Repo, key *PublicKey, name, value string) error {
+	encrypted, err := EncryptSecret(key.Key, value)
+	if err != nil {
+		return err
+	}
+	return c.do("PUT", r.ActionsPath()+"/secrets/"+url.PathEscape(name), map[string]string{
+		"encrypted_value": encrypted,
+		"key_id":          key.KeyID,
+	}, nil)
+}
+
+// EncryptSecret seals value for the base64-encoded Curve25519 public key, as
+// libsodium's crypto_box_seal does.
+func EncryptSecret(publicKey, value string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("invalid public key length %d", len(raw))
+	}
+	var recipient [32]byte
+	copy(recipient[:], raw)
+
+	sealed, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Variable is an Actions configuration variable.
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ListVariables returns all variables, following pagination.
+func (c *Client) ListVariables(r Repo) ([]Variable, error) {
+	var all []Variable
+	for path := r.ActionsPath() + "/variables?per_page=30"; path != ""; {
+		var resp struct {
+			Variables []Variable `json:"variables"`
+		}
+		next, err := c.page(path, &resp)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Variables...)
+		path = next
+	}
+	return all, nil
+}
+
+// CreateVariable creates a new variable.
+func (c *Client) CreateVariable(r Repo, name, value string) error {
+	return c.do("POST", r.ActionsPath()+"/variables", Variable{Name: name, Value: value}, nil)
+}
+
+// UpdateVariable changes the value of an existing variable.
+func (c *Client) UpdateVariable(r Repo, name, value string) error {
+	return c.do("PATCH", r.ActionsPath()+"/variables/"+url.PathEscape(name), Variable{Name: name, Value: value}, nil)
+}
+
+// Reviewer is a user or team whose approval an environment requires.
+type Reviewer struct {
+	Type string `json:"type"` // "User" or "Team"
+	ID   int64  `json:"id"`
+}
+
+// BranchPolicy restricts which refs may deploy to an environment. With
+// CustomBranchPolicies set, the environment's deployment branch policies apply.
+type BranchPolicy struct {
+	ProtectedBranches    bool `json:"protected_branches"`
+	CustomBranchPolicies bool `json:"custom_branch_policies"`
+}
+
+// EnvironmentSettings are the protection rules of an environment.
+type EnvironmentSettings struct {
+	WaitTimer              int           `json:"wait_timer"`
+	Reviewers              []Reviewer    `json:"reviewers"`
+	DeploymentBranchPolicy *BranchPolicy `json:"deployment_branch_policy"`
+}
+
+// CreateEnvironment creates or updates the environment. With nil settings an
+// existing environment is left as is.
+func (c *Client) CreateEnvironment(r Repo, name string, settings *EnvironmentSettings) error {
+	var in any
+	if settings != nil {
+		in = settings
+	}
+	return c.do("PUT", r.path()+"/environments/"+url.PathEscape(name), in, nil)
+}
+
+// DeploymentPolicy is a branch or tag name pattern allowed to deploy to an
+// environment.
+type DeploymentPolicy struct {
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name"`
+	Type string `json:"type"` // "branch" or "tag"
+}
+
+func (r Repo) policiesPath(env string) string {
+	return r.path() + "/environments/" + url.PathEscape(env) + "/deployment-branch-policies"
+}
+
+// ListDeploymentPolicies returns the deployment branch and tag policies of env.
+func (c *Client) ListDeploymentPolicies(r Repo, env string) ([]DeploymentPolicy, error) {
+	var all []DeploymentPolicy
+	for path := r.policiesPath(env) + "?per_page=100"; path != ""; {
+		var resp struct {
+			Policies []DeploymentPolicy `json:"branch_policies"`
+		}
+		next, err := c.page(path, &resp)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, resp.Policies...)
+		path = next
+	}
+	return all, nil
+}
+
+// CreateDeploymentPolicy allows refs matching p to deploy to env.
+func (c *Client) CreateDeploymentPolicy(r Repo, env string, p DeploymentPolicy) error {
+	return c.do("POST", r.policiesPath(env), DeploymentPolicy{Name: p.Name, Type: p.Type}, nil)
+}
+
+// DeleteDeploymentPolicy removes a deployment branch or tag policy.
+func (c *Client) DeleteDeploymentPolicy(r Repo, env string, id int64) error {
+	return c.do("DELETE", fmt.Sprintf("%s/%d", r.policiesPath(env), id), nil, nil)
+}
+
+// UserID resolves a login to its numeric ID.
+func (c *Client) UserID(login string) (int64, error) {
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do("GET", "/users/"+url.PathEscape(login), nil, &user); err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// TeamID resolves an organization team slug to its numeric ID.
+func (c *Client) TeamID(org, slug string) (int64, error) {
+	var team struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do("GET", "/orgs/"+url.PathEscape(org)+"/teams/"+url.PathEscape(slug), nil, &team); err != nil {
+		return 0, err
+	}
+	return team.ID, nil
+}
+
+// Ruleset is a repository ruleset. Rules are kept as raw objects since each
+// rule type has its own parameters.
+type Ruleset struct {
+	ID          int64             `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Target      string            `json:"target"`
+	Enforcement string            `json:"enforcement"`
+	Conditions  RulesetConditions `json:"conditions"`
+	Rules       []Rule            `json:"rules"`
+}
+
+// RulesetConditions select the refs a ruleset applies to. "~DEFAULT_BRANCH"
+// matches the default branch.
+type RulesetConditions struct {
+	RefName struct {
+		Include []string `json:"include"`
+		Exclude []string `json:"exclude"`
+	} `json:"ref_name"`
+}
+
+// Rule is a single ruleset rule, e.g. {"type": "pull_request"}.
+type Rule struct {
+	Type       string         `json:"type"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+}
+
+// ListRulesets returns the rulesets defined on the repository itself.
+func (c *Client) ListRulesets(r Repo) ([]Ruleset, error) {
+	var all []Ruleset
+	for path := r.path() + "/rulesets?includes_parents=false&per_page=100"; path != ""; {
+		var rulesets []Ruleset
+		next, err := c.page(path, &rulesets)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, rulesets...)
+		path = next
+	}
+	return all, nil
+}
+
+// CreateRuleset creates a repository ruleset.
+func (c *Client) CreateRuleset(r Repo, rs Ruleset) error {
+	return c.do("POST", r.path()+"/rulesets", rs, nil)
+}
+
+// UpdateRuleset replaces the ruleset with the given ID.
+func (c *Client) UpdateRuleset(r Repo, id int64, rs Ruleset) error {
+	rs.ID = 0
+	return c.do("PUT", fmt.Sprintf("%s/rulesets/%d", r.path(), id), rs, nil)
+}
@@ -0,0 +1,334 @@
+package github
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// newTestClient returns a client for an httptest server running handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+}
+
+// pagedHandler serves pages of a list at path, linking each page to the next
+// with a Link header. body renders the items of one page.
+func pagedHandler(t *testing.T, path string, pages [][]string,
+	body func(items []string) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			_, _ = fmt.Sscanf(p, "%d", &page)
+		}
+		if page < 1 || page > len(pages) {
+			t.Errorf("page %d out of range", page)
+			http.NotFound(w, r)
+			return
+		}
+		if page < len(pages) {
+			next := fmt.Sprintf("http://%s%s?per_page=2&page=%d", r.Host, path, page+1)
+			last := fmt.Sprintf("http://%s%s?per_page=2&page=%d", r.Host, path, len(pages))
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, next, last))
+		}
+		_ = json.NewEncoder(w).Encode(body(pages[page-1]))
+	}
+}
+
+func TestListSecretsFollowsLinkHeader(t *testing.T) {
+	pages := [][]string{{"A", "B"}, {"C", "D"}, {"E"}}
+	c := newTestClient(t, pagedHandler(t, "/repos/acme/shop/actions/secrets", pages,
+		func(items []string) any {
+			var secrets []Secret
+			for _, name := range items {
+				secrets = append(secrets, Secret{Name: name})
+			}
+			// A total_count smaller than the real one must not stop pagination.
+			return map[string]any{"total_count": 2, "secrets": secrets}
+		}))
+
+	secrets, err := c.ListSecrets(Repo{Owner: "acme", Name: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range secrets {
+		names = append(names, s.Name)
+	}
+	if want := []string{"A", "B", "C", "D", "E"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestListVariablesFollowsLinkHeader(t *testing.T) {
+	pages := [][]string{{"GCP_PROJECT_ID", "GCP_REGION"}, {"GCP_CLOUD_RUN_SERVICE"}}
+	c := newTestClient(t, pagedHandler(t, "/repos/acme/shop/environments/production/variables", pages,
+		func(items []string) any {
+			var vars []Variable
+			for _, name := range items {
+				vars = append(vars, Variable{Name: name, Value: "v-" + name})
+			}
+			return map[string]any{"total_count": 3, "variables": vars}
+		}))
+
+	vars, err := c.ListVariables(Repo{Owner: "acme", Name: "shop", Environment: "production"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Variable{
+		{"GCP_PROJECT_ID", "v-GCP_PROJECT_ID"},
+		{"GCP_REGION", "v-GCP_REGION"},
+		{"GCP_CLOUD_RUN_SERVICE", "v-GCP_CLOUD_RUN_SERVICE"},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("got %v, want %v", vars, want)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`,
+			"https://api.github.com/x?page=2"},
+		{`<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=3>; rel="next"`,
+			"https://api.github.com/x?page=3"},
+		{`<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=1>; rel="prev"`, ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.link); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestSetSecret(t *testing.T) {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &PublicKey{KeyID: "key-123", Key: base64.StdEncoding.EncodeToString(pub[:])}
+
+	var got struct {
+		EncryptedValue string `json:"encrypted_value"`
+		KeyID          string `json:"key_id"`
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/repos/acme/shop/environments/staging/secrets/GCP_SA" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err = c.SetSecret(Repo{Owner: "acme", Name: "shop", Environment: "staging"}, key, "GCP_SA", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.KeyID != "key-123" {
+		t.Errorf("key_id = %q, want key-123", got.KeyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(got.EncryptedValue)
+	if err != nil {
+		t.Fatalf("encrypted_value is not base64: %v", err)
+	}
+	plain, ok := box.OpenAnonymous(nil, sealed, pub, priv)
+	if !ok {
+		t.Fatal("encrypted_value does not open with the private key")
+	}
+	if string(plain) != "s3cret" {
+		t.Errorf("decrypted %q, want s3cret", plain)
+	}
+}
+
+func TestEncryptSecretInvalidKey(t *testing.T) {
+	for _, key := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := EncryptSecret(key, "value"); err == nil {
+			t.Errorf("EncryptSecret(%q) succeeded", key)
+		}
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		notFound bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusUnprocessableEntity, false},
+		{http.StatusForbidden, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message":"nope"}`, tt.status)
+		})
+
+		_, err := c.PublicKey(Repo{Owner: "acme", Name: "shop"})
+		se, ok := err.(*StatusError)
+		if !ok {
+			t.Fatalf("status %d: got %T %v, want *StatusError", tt.status, err, err)
+		}
+		if se.StatusCode != tt.status || se.Method != "GET" ||
+			se.Path != "/repos/acme/shop/actions/secrets/public-key" || se.Body != `{"message":"nope"}` {
+			t.Errorf("status %d: unexpected error %+v", tt.status, se)
+		}
+		if IsNotFound(err) != tt.notFound {
+			t.Errorf("status %d: IsNotFound = %v", tt.status, !tt.notFound)
+		}
+		if IsNotFound(fmt.Errorf("wrapped: %w", err)) != tt.notFound {
+			t.Errorf("status %d: IsNotFound does not unwrap", tt.status)
+		}
+	}
+}
+
+func TestListStopsOnError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=2>; rel="next"`, r.Host, r.URL.Path))
+		_, _ = w.Write([]byte(`{"secrets":[{"name":"A"}]}`))
+	})
+	if _, err := c.ListSecrets(Repo{Owner: "acme", Name: "shop"}); err == nil {
+		t.Fatal("expected the error of the second page")
+	}
+}
+
+func TestListRefusesForeignNextLink(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(host string) string
+	}{
+		{"other host", func(string) string { return "http://evil.example/steal?page=2" }},
+		{"other scheme", func(host string) string { return "https://" + host + "/x?page=2" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, tt.link(r.Host)))
+				_, _ = w.Write([]byte(`{"secrets":[{"name":"A"}]}`))
+			})
+			if _, err := c.ListSecrets(Repo{Owner: "acme", Name: "shop"}); err == nil {
+				t.Error("expected an error for the next link")
+			}
+			if requests != 1 {
+				t.Errorf("%d requests, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	tests := map[string]string{
+		"":                   "https://api.github.com",
+		"github.com":         "https://api.github.com",
+		"acme.ghe.com":       "https://api.acme.ghe.com",
+		"github.example.com": "https://github.example.com/api/v3",
+	}
+	for host, want := range tests {
+		if got := APIURL(host); got != want {
+			t.Errorf("APIURL(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	hosts := `github.com:
+    user: octocat
+    oauth_token: hosts-github
+github.example.com:
+    oauth_token: hosts-enterprise
+`
+	tests := []struct {
+		name    string
+		host    string
+		env     map[string]string
+		hosts   bool
+		gh      string // output of the fake gh auth token, "" for none
+		want    string
+		wantErr bool
+	}{
+		{name: "GH_TOKEN first", host: "github.com",
+			env: map[string]string{"GH_TOKEN": "gh", "GITHUB_TOKEN": "github"}, hosts: true, gh: "cli", want: "gh"},
+		{name: "GITHUB_TOKEN second", host: "github.com",
+			env: map[string]string{"GITHUB_TOKEN": "github"}, hosts: true, gh: "cli", want: "github"},
+		{name: "GHE.com uses the github.com variables", host: "acme.ghe.com",
+			env: map[string]string{"GH_TOKEN": "gh", "GH_ENTERPRISE_TOKEN": "enterprise"}, want: "gh"},
+		{name: "enterprise variables for GHES", host: "github.example.com",
+			env: map[string]string{"GH_TOKEN": "gh", "GITHUB_ENTERPRISE_TOKEN": "enterprise"}, hosts: true,
+			want: "enterprise"},
+		{name: "hosts.yml before gh", host: "github.com", hosts: true, gh: "cli", want: "hosts-github"},
+		{name: "hosts.yml entry of the host", host: "github.example.com",
+			env: map[string]string{"GH_TOKEN": "gh"}, hosts: true, want: "hosts-enterprise"},
+		{name: "gh auth token last", host: "github.com", gh: "cli", want: "cli"},
+		{name: "no token", host: "github.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+				t.Setenv(v, tt.env[v])
+			}
+
+			dir := t.TempDir()
+			t.Setenv("GH_CONFIG_DIR", dir)
+			if tt.hosts {
+				if err := os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(hosts), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			bin := t.TempDir()
+			t.Setenv("PATH", bin)
+			if tt.gh != "" {
+				script := "#!/bin/sh\necho " + tt.gh + "\n"
+				if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := Token(tt.host)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}